@@ -0,0 +1,717 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"FileRenUtil/engine"
+)
+
+/* -------------------- Headless CLI -------------------- */
+
+// cliCommands are the subcommands that run RenForge without the Fyne window.
+// Anything else (including the -psn_* argument macOS passes to app bundles)
+// falls through to the GUI.
+var cliCommands = map[string]bool{
+	"plan":    true,
+	"apply":   true,
+	"undo":    true,
+	"recover": true,
+	"help":    true,
+}
+
+func isCLICommand(args []string) bool {
+	return len(args) > 0 && cliCommands[args[0]]
+}
+
+// short names accepted on the command line in addition to the UI labels
+var cliFilterModes = map[string]string{
+	"contains":    "contains",
+	"starts":      "starts with",
+	"starts with": "starts with",
+	"ends":        "ends with",
+	"ends with":   "ends with",
+	"ext":         "extension",
+	"extension":   "extension",
+	"regex":       "regex",
+	"glob":        "glob",
+	"path":        "path",
+	"size":        "size",
+	"modified":    "modified",
+	"mtime":       "modified",
+	"created":     "created",
+	"ctime":       "created",
+	"hidden":      "hidden",
+	"read-only":   "read-only",
+	"readonly":    "read-only",
+	"symlink":     "symlink",
+}
+
+var cliStepOps = map[string]engine.RenameOp{
+	"remove":   engine.OpRemoveText,
+	"replace":  engine.OpReplaceText,
+	"insert":   engine.OpInsertBeforeExt,
+	"ext":      engine.OpChangeExt,
+	"append":   engine.OpAppend,
+	"prepend":  engine.OpPrepend,
+	"regex":    engine.OpRegexReplace,
+	"number":   engine.OpNumber,
+	"template": engine.OpTemplate,
+	"case":     engine.OpChangeCase,
+	"unicode":  engine.OpNormalize,
+	"sanitize": engine.OpSanitize,
+}
+
+var cliUnicodeModes = map[string]string{
+	"nfc":   engine.UnicodeNFC,
+	"nfd":   engine.UnicodeNFD,
+	"nfkc":  engine.UnicodeNFKC,
+	"strip": engine.UnicodeStripMarks,
+	"ascii": engine.UnicodeTranslit,
+}
+
+var cliCollisions = map[string]string{
+	"skip":       engine.CollisionSkip,
+	"counter":    engine.CollisionCounter,
+	"underscore": engine.CollisionUnderscore,
+	"overwrite":  engine.CollisionOverwrite,
+	"newer":      engine.CollisionKeepNewer,
+	"larger":     engine.CollisionKeepLarger,
+}
+
+var cliProfiles = map[string]string{
+	"linux":    engine.ProfileLinux,
+	"macos":    engine.ProfileMacOS,
+	"windows":  engine.ProfileWindows,
+	"fat":      engine.ProfileFAT,
+	"exfat":    engine.ProfileFAT,
+	"portable": engine.ProfilePortable,
+}
+
+var cliCaseFS = map[string]string{
+	"auto":        engine.CaseFSAuto,
+	"sensitive":   engine.CaseFSSensitive,
+	"insensitive": engine.CaseFSInsensitive,
+}
+
+// repeatable string flag (--filter a --filter b)
+type multiFlag []string
+
+func (m *multiFlag) String() string     { return strings.Join(*m, ", ") }
+func (m *multiFlag) Set(v string) error { *m = append(*m, v); return nil }
+
+const cliUsage = `Usage:
+  renforge plan  --folder DIR [options]
+  renforge apply --folder DIR [options] [--dry-run=false]
+  renforge undo  --log FILE [--dry-run=false] [--undo-log FILE]
+  renforge recover --folder DIR [--recursive] [--roll forward|back]
+
+Options:
+  --folder DIR          folder to scan (required)
+  --preset FILE         start from an exported preset; other flags
+                        override its options and add filters/steps
+  --recursive           include subfolders
+  --max-depth N         with --recursive, descend at most N folder levels
+  --exclude GLOB        skip folders matching GLOB, repeatable
+                        (e.g. node_modules, .git, build/**)
+  --skip-hidden         leave out dot-files and dot-folders
+  --gitignore           honour .gitignore and .ignore files
+  --follow-links        descend into symlinked folders (each folder once)
+  --filter MODE:VALUE   filter rule, repeatable (contains, starts, ends, ext,
+                        regex, glob, path, size, modified, created, hidden,
+                        read-only, symlink); glob and path match the path
+                        relative to --folder (glob **/raw/*.CR2, path raw/);
+                        size takes e.g. >10MB or 1MB-5MB, dates
+                        take e.g. "last 7 days", >2024-01-01 or
+                        2024-01-01..2024-03-31, the others yes or no;
+                        prefix with ! to keep the files a rule doesn't
+                        match, e.g. '!contains:thumb'
+  --match all|any       combine filters with AND (default) or OR (a preset
+                        keeps its nested groups; this sets the outer one)
+  --case-sensitive      case sensitive filters
+  --normalize-match     compare filters in NFKC, so composed and decomposed
+                        accents match alike
+  --step OP:A[:B]       rename step, repeatable, applied in order
+                        (remove, replace, insert, ext, append, prepend, regex,
+                        iregex, number, template, case, unicode, sanitize);
+                        iregex is regex ignoring case; number takes
+                        SEP:KEY=VAL,...
+                        with keys start, inc, pad (digits or auto), pos
+                        (prepend, before-ext, or after-ext/append for after
+                        the extension), sort (name, natural,
+                        mtime, size) and per-folder; case takes STYLE[:EXT]
+                        with STYLE upper, lower, title, sentence, camel,
+                        snake or kebab and EXT keep, lower or upper;
+                        unicode takes nfc, nfd, nfkc, strip (diacritics)
+                        or ascii (transliterate); sanitize takes toggles
+                        url, space, punct, emoji, slug and remove (drop
+                        invalid characters instead of using _), default
+                        url,space,punct
+  --on-conflict POLICY  when a target is taken: skip (default), counter
+                        ("a (2).txt"), underscore ("a_2.txt"), overwrite
+                        (existing file kept as NAME.bak), newer or larger
+                        (keep that file, back up the existing one)
+  --profile NAME        naming rules new names must follow: linux, macos,
+                        windows, fat (FAT32/exFAT) or portable; defaults
+                        to this system
+  --case-fs MODE        whether names differing only in case collide: auto
+                        (probe each folder, default), sensitive or
+                        insensitive
+  --dry-run             apply/undo: don't rename (default true)
+  --undo-log FILE       apply/undo: write the undo CSV to FILE
+  --script FILE         plan/apply: also export the plan as a script
+                        (.sh, .ps1, .bat or .cmd)
+  --log FILE            undo: the undo CSV of the batch to revert
+  --roll forward|back   recover: finish or undo renames interrupted by a
+                        crash; without it recover only reports them
+
+Exit status is 0 when every selected file can be renamed, 1 when any file
+is skipped or fails, and 2 on usage errors.
+`
+
+// runCLI runs a headless subcommand and returns the process exit code.
+func runCLI(args []string, stdout, stderr io.Writer) int {
+	cmd := args[0]
+	switch cmd {
+	case "help":
+		fmt.Fprint(stdout, cliUsage)
+		return 0
+	case "undo":
+		return runCLIUndo(args[1:], stdout, stderr)
+	case "recover":
+		return runCLIRecover(args[1:], stdout, stderr)
+	}
+
+	fs := flag.NewFlagSet("renforge "+cmd, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { fmt.Fprint(stderr, cliUsage) }
+
+	var filters, steps, excludes multiFlag
+	folder := fs.String("folder", "", "")
+	recursive := fs.Bool("recursive", false, "")
+	maxDepth := fs.Int("max-depth", 0, "")
+	skipHidden := fs.Bool("skip-hidden", false, "")
+	useIgnore := fs.Bool("gitignore", false, "")
+	followLinks := fs.Bool("follow-links", false, "")
+	match := fs.String("match", "all", "")
+	caseSensitive := fs.Bool("case-sensitive", false, "")
+	normalizeMatch := fs.Bool("normalize-match", false, "")
+	dryRun := fs.Bool("dry-run", true, "")
+	undoLog := fs.String("undo-log", "", "")
+	presetPath := fs.String("preset", "", "")
+	scriptPath := fs.String("script", "", "")
+	onConflict := fs.String("on-conflict", "skip", "")
+	profileName := fs.String("profile", "", "")
+	caseFS := fs.String("case-fs", "auto", "")
+	fs.Var(&filters, "filter", "")
+	fs.Var(&steps, "step", "")
+	fs.Var(&excludes, "exclude", "")
+
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "unexpected argument: %s\n", fs.Arg(0))
+		return 2
+	}
+	if strings.TrimSpace(*folder) == "" {
+		fmt.Fprintln(stderr, "--folder is required")
+		return 2
+	}
+	// absolute paths keep undo logs and scripts usable from any directory,
+	// and give {parent} a real name for "--folder ."
+	if abs, err := filepath.Abs(*folder); err == nil {
+		*folder = abs
+	}
+	if *match != "all" && *match != "any" {
+		fmt.Fprintf(stderr, "--match must be all or any, got %q\n", *match)
+		return 2
+	}
+	if *maxDepth < 0 {
+		fmt.Fprintf(stderr, "--max-depth must be 0 (no limit) or more, got %d\n", *maxDepth)
+		return 2
+	}
+
+	collision, ok := cliCollisions[strings.ToLower(*onConflict)]
+	if !ok {
+		fmt.Fprintf(stderr, "--on-conflict must be skip, counter, underscore, overwrite, newer or larger, got %q\n", *onConflict)
+		return 2
+	}
+
+	profile := engine.DefaultProfile()
+	if *profileName != "" {
+		if profile, ok = cliProfiles[strings.ToLower(*profileName)]; !ok {
+			fmt.Fprintf(stderr, "--profile must be linux, macos, windows, fat or portable, got %q\n", *profileName)
+			return 2
+		}
+	}
+
+	caseMode, ok := cliCaseFS[strings.ToLower(*caseFS)]
+	if !ok {
+		fmt.Fprintf(stderr, "--case-fs must be auto, sensitive or insensitive, got %q\n", *caseFS)
+		return 2
+	}
+
+	opts := engine.Options{
+		Folder:    *folder,
+		Recursive: *recursive,
+		Scan: engine.ScanOptions{
+			MaxDepth:    *maxDepth,
+			Exclude:     excludes,
+			SkipHidden:  *skipHidden,
+			UseIgnore:   *useIgnore,
+			FollowLinks: *followLinks,
+		},
+		Filters:        engine.FilterGroup{MatchAll: *match == "all"},
+		CaseSensitive:  *caseSensitive,
+		NormalizeMatch: *normalizeMatch,
+		Collision:      collision,
+		Profile:        profile,
+		CaseFS:         caseMode,
+	}
+
+	// a preset is the starting point; flags given explicitly override its
+	// options and add filters/steps/excludes after its own
+	if *presetPath != "" {
+		p, err := engine.LoadPresetFile(*presetPath)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		p.ApplyTo(&opts)
+		opts.Scan.Exclude = append(opts.Scan.Exclude, excludes...)
+		fs.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "recursive":
+				opts.Recursive = *recursive
+			case "max-depth":
+				opts.Scan.MaxDepth = *maxDepth
+			case "skip-hidden":
+				opts.Scan.SkipHidden = *skipHidden
+			case "gitignore":
+				opts.Scan.UseIgnore = *useIgnore
+			case "follow-links":
+				opts.Scan.FollowLinks = *followLinks
+			case "match":
+				opts.Filters.MatchAll = *match == "all"
+			case "case-sensitive":
+				opts.CaseSensitive = *caseSensitive
+			case "normalize-match":
+				opts.NormalizeMatch = *normalizeMatch
+			}
+		})
+	}
+
+	for _, raw := range filters {
+		rule, err := parseCLIFilter(raw)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		opts.Filters.Rules = append(opts.Filters.Rules, rule)
+	}
+	for _, raw := range steps {
+		step, err := parseCLIStep(raw)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		step.ID = len(opts.Steps) + 1
+		opts.Steps = append(opts.Steps, step)
+	}
+
+	files, skipped, err := engine.ScanContext(context.Background(), opts, nil)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	for _, err := range skipped {
+		fmt.Fprintln(stderr, "skipped:", err)
+	}
+	matched := engine.Filter(files, opts)
+
+	plan, summary := engine.Plan(matched, opts)
+	if *scriptPath != "" {
+		if err := writeCLIScript(*scriptPath, plan); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+	}
+	return executeCLIPlan(plan, summary, cmd == "apply", *dryRun, *undoLog, stdout, stderr)
+}
+
+// writeCLIScript exports plan as a script, choosing the format from the
+// file extension (.sh, .ps1, .bat or .cmd).
+func writeCLIScript(path string, plan []engine.RenamePlanItem) error {
+	ext := strings.ToLower(filepath.Ext(path))
+	kind := ""
+	for k, e := range engine.ScriptExt {
+		if e == ext {
+			kind = k
+		}
+	}
+	if ext == ".cmd" {
+		kind = engine.ScriptBatch
+	}
+	if kind == "" {
+		return fmt.Errorf("--script %s: use a .sh, .ps1, .bat or .cmd file", path)
+	}
+
+	script, err := engine.ExportScript(plan, kind)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(script), 0o755)
+}
+
+// runCLIUndo reverts the "renamed" rows of an undo log.
+func runCLIUndo(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("renforge undo", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { fmt.Fprint(stderr, cliUsage) }
+
+	logPath := fs.String("log", "", "")
+	dryRun := fs.Bool("dry-run", true, "")
+	undoLog := fs.String("undo-log", "", "")
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "unexpected argument: %s\n", fs.Arg(0))
+		return 2
+	}
+	if strings.TrimSpace(*logPath) == "" {
+		fmt.Fprintln(stderr, "--log is required")
+		return 2
+	}
+
+	rows, err := engine.ReadUndoCSVFile(*logPath)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	plan, summary := engine.PlanUndo(rows)
+	return executeCLIPlan(plan, summary, true, *dryRun, *undoLog, stdout, stderr)
+}
+
+// runCLIRecover reports renames an interrupted apply left behind under
+// --folder and, with --roll, finishes or undoes them.
+func runCLIRecover(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("renforge recover", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { fmt.Fprint(stderr, cliUsage) }
+
+	folder := fs.String("folder", "", "")
+	recursive := fs.Bool("recursive", false, "")
+	roll := fs.String("roll", "", "")
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "unexpected argument: %s\n", fs.Arg(0))
+		return 2
+	}
+	if strings.TrimSpace(*folder) == "" {
+		fmt.Fprintln(stderr, "--folder is required")
+		return 2
+	}
+	if *roll != "" && *roll != "forward" && *roll != "back" {
+		fmt.Fprintf(stderr, "--roll must be forward or back, got %q\n", *roll)
+		return 2
+	}
+
+	rec, err := engine.FindRecovery(*folder, *recursive)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if rec.Empty() {
+		fmt.Fprintln(stdout, "Nothing to recover.")
+		return 0
+	}
+	fmt.Fprint(stdout, engine.FormatRecovery(rec))
+	if *roll == "" || len(rec.Journals) == 0 {
+		return 1
+	}
+
+	var results []engine.RenamePlanItem
+	code := 0
+	for _, j := range rec.Journals {
+		out, err := j.Recover(*roll == "forward")
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			code = 1
+		}
+		results = append(results, out...)
+	}
+	fmt.Fprintln(stdout)
+	for _, it := range results {
+		if it.Status == engine.StatusError {
+			fmt.Fprintf(stderr, "%s → %s: %s\n", it.OldPath, it.NewName, it.Reason)
+			code = 1
+		}
+	}
+	fmt.Fprintln(stdout, engine.FormatResult(results, false))
+	if len(rec.Orphans) > 0 || len(rec.Errors) > 0 {
+		code = 1
+	}
+	return code
+}
+
+// executeCLIPlan prints the plan, runs it when execute is set (as a dry run
+// unless dryRun is false) and returns the exit code.
+func executeCLIPlan(plan []engine.RenamePlanItem, summary engine.PlanSummary, execute, dryRun bool, undoLog string, stdout, stderr io.Writer) int {
+	for _, it := range plan {
+		if it.Status == engine.StatusOK {
+			fmt.Fprintf(stdout, "%s → %s\n", it.OldPath, it.NewName)
+		}
+	}
+	if len(plan) > 0 {
+		fmt.Fprintln(stdout)
+	}
+	fmt.Fprint(stdout, engine.FormatSummary(summary))
+
+	results := plan
+	if execute {
+		if dryRun {
+			engine.MarkDryRun(results)
+		} else {
+			results = engine.Apply(plan)
+		}
+		fmt.Fprintln(stdout, engine.FormatResult(results, dryRun))
+
+		if undoLog != "" {
+			if err := engine.WriteUndoCSVFile(undoLog, results); err != nil {
+				fmt.Fprintln(stderr, err)
+				return 1
+			}
+			fmt.Fprintf(stdout, "Undo CSV: %s\n", undoLog)
+		}
+	}
+
+	// files that already have their new name are not a failure
+	for _, it := range results {
+		if it.Status == engine.StatusError || (it.Status == engine.StatusSkip && it.Reason != engine.ReasonUnchanged) {
+			return 1
+		}
+	}
+	return 0
+}
+
+func parseCLIFilter(raw string) (engine.FilterRule, error) {
+	spec, not := strings.CutPrefix(raw, "!")
+	mode, value, hasValue := strings.Cut(spec, ":")
+	m, ok := cliFilterModes[strings.ToLower(strings.TrimSpace(mode))]
+	if !hasValue && m != "hidden" && m != "read-only" && m != "symlink" {
+		// only the yes/no modes may leave the value out ("--filter hidden")
+		return engine.FilterRule{}, fmt.Errorf("--filter %q: expected MODE:VALUE", raw)
+	}
+	if !ok {
+		return engine.FilterRule{}, fmt.Errorf("--filter %q: unknown mode %q", raw, mode)
+	}
+	rule := engine.FilterRule{Mode: m, Value: value, Not: not}
+	if err := engine.ValidateFilter(rule); err != nil {
+		return engine.FilterRule{}, fmt.Errorf("--filter %q: %v", raw, err)
+	}
+	return rule, nil
+}
+
+func parseCLIStep(raw string) (engine.RenameStep, error) {
+	parts := strings.SplitN(raw, ":", 3)
+	name := strings.ToLower(strings.TrimSpace(parts[0]))
+	// iregex is regex ignoring case
+	ignoreCase := name == "iregex"
+	if ignoreCase {
+		name = "regex"
+	}
+	op, ok := cliStepOps[name]
+	if !ok {
+		for _, known := range cliStepOps {
+			if strings.EqualFold(string(known), name) {
+				op, ok = known, true
+				break
+			}
+		}
+	}
+	if !ok {
+		return engine.RenameStep{}, fmt.Errorf("--step %q: unknown operation %q", raw, parts[0])
+	}
+
+	step := engine.RenameStep{Op: op, IgnoreCase: ignoreCase}
+	switch op {
+	case engine.OpReplaceText, engine.OpRegexReplace, engine.OpNumber, engine.OpChangeCase:
+		if len(parts) > 1 {
+			step.A = parts[1]
+		}
+		if len(parts) > 2 {
+			step.B = parts[2]
+		}
+	default:
+		// single-argument steps take everything after the op, colons included
+		_, step.A, _ = strings.Cut(raw, ":")
+	}
+	if op == engine.OpNumber {
+		num, err := parseCLINumbering(step.B)
+		if err != nil {
+			return engine.RenameStep{}, fmt.Errorf("--step %q: %v", raw, err)
+		}
+		step.Num, step.B = num, ""
+	}
+	if op == engine.OpChangeCase {
+		c, err := parseCLICase(step.A, step.B)
+		if err != nil {
+			return engine.RenameStep{}, fmt.Errorf("--step %q: %v", raw, err)
+		}
+		step.Case, step.A, step.B = c, "", ""
+	}
+	if op == engine.OpNormalize {
+		mode, ok := cliUnicodeModes[strings.ToLower(step.A)]
+		if !ok {
+			return engine.RenameStep{}, fmt.Errorf("--step %q: unicode takes nfc, nfd, nfkc, strip or ascii", raw)
+		}
+		step.A = mode
+	}
+	if op == engine.OpSanitize {
+		opts, err := parseCLISanitize(step.A)
+		if err != nil {
+			return engine.RenameStep{}, fmt.Errorf("--step %q: %v", raw, err)
+		}
+		step.Sanitize, step.A = opts, ""
+	}
+	if err := engine.ValidateStep(step); err != nil {
+		return engine.RenameStep{}, fmt.Errorf("--step %q: %v", raw, err)
+	}
+	return step, nil
+}
+
+var cliNumberPositions = map[string]string{
+	"prepend":    engine.NumPrepend,
+	"append":     engine.NumAppend,
+	"after-ext":  engine.NumAppend,
+	"before-ext": engine.NumBeforeExt,
+}
+
+var cliNumberSorts = map[string]string{
+	"name":    engine.SortByName,
+	"natural": engine.SortByNatural,
+	"mtime":   engine.SortByModified,
+	"size":    engine.SortBySize,
+}
+
+// parseCLINumbering reads "start=1,inc=1,pad=auto,pos=append,sort=natural,per-folder".
+func parseCLINumbering(raw string) (engine.NumberingOptions, error) {
+	num := engine.DefaultNumbering()
+	for _, kv := range strings.Split(raw, ",") {
+		kv = strings.TrimSpace(kv)
+		if kv == "" {
+			continue
+		}
+		key, val, _ := strings.Cut(kv, "=")
+		var err error
+		switch key {
+		case "start":
+			num.Start, err = strconv.Atoi(val)
+		case "inc":
+			num.Increment, err = strconv.Atoi(val)
+		case "pad":
+			if strings.EqualFold(val, "auto") {
+				num.Padding = engine.PadAuto
+			} else {
+				num.Padding, err = strconv.Atoi(val)
+			}
+		case "pos":
+			var ok bool
+			if num.Position, ok = cliNumberPositions[val]; !ok {
+				err = fmt.Errorf("unknown position %q", val)
+			}
+		case "sort":
+			var ok bool
+			if num.SortBy, ok = cliNumberSorts[val]; !ok {
+				err = fmt.Errorf("unknown sort key %q", val)
+			}
+		case "per-folder":
+			num.PerFolder = true
+		default:
+			err = fmt.Errorf("unknown numbering option %q", key)
+		}
+		if err != nil {
+			return engine.NumberingOptions{}, err
+		}
+	}
+	return num, nil
+}
+
+var cliCaseStyles = map[string]string{
+	"upper":    engine.CaseUpper,
+	"lower":    engine.CaseLower,
+	"title":    engine.CaseTitle,
+	"sentence": engine.CaseSentence,
+	"camel":    engine.CaseCamel,
+	"snake":    engine.CaseSnake,
+	"kebab":    engine.CaseKebab,
+}
+
+var cliExtCases = map[string]string{
+	"keep":  engine.ExtKeep,
+	"lower": engine.ExtLower,
+	"upper": engine.ExtUpper,
+}
+
+// parseCLISanitize reads the toggles of a sanitize step, e.g.
+// "url,emoji,slug"; none at all gives engine.DefaultSanitize.
+func parseCLISanitize(spec string) (engine.SanitizeOptions, error) {
+	if strings.TrimSpace(spec) == "" {
+		return engine.DefaultSanitize(), nil
+	}
+	opts := engine.SanitizeOptions{Invalid: engine.SanitizeReplace}
+	for _, key := range strings.Split(spec, ",") {
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "url":
+			opts.URLDecode = true
+		case "space":
+			opts.CollapseSpace = true
+		case "punct":
+			opts.TrimPunct = true
+		case "emoji":
+			opts.RemoveEmoji = true
+		case "slug":
+			opts.Slug = true
+		case "remove":
+			opts.Invalid = engine.SanitizeRemove
+		default:
+			return engine.SanitizeOptions{}, fmt.Errorf("unknown sanitize option %q", key)
+		}
+	}
+	return opts, nil
+}
+
+func parseCLICase(style, ext string) (engine.CaseOptions, error) {
+	c := engine.DefaultCase()
+	var ok bool
+	if c.Style, ok = cliCaseStyles[strings.ToLower(style)]; !ok {
+		return engine.CaseOptions{}, fmt.Errorf("unknown case style %q", style)
+	}
+	if ext != "" {
+		if c.Ext, ok = cliExtCases[strings.ToLower(ext)]; !ok {
+			return engine.CaseOptions{}, fmt.Errorf("unknown extension case %q", ext)
+		}
+	}
+	return c, nil
+}
+
+// runCLIIfRequested is called first thing in main so the GUI code never
+// starts for headless invocations.
+func runCLIIfRequested() {
+	if !isCLICommand(os.Args[1:]) {
+		return
+	}
+	os.Exit(runCLI(os.Args[1:], os.Stdout, os.Stderr))
+}
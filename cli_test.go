@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"FileRenUtil/engine"
+)
+
+// cliFolder creates the named empty files in a temp folder.
+func cliFolder(t *testing.T, names ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestRunCLIExitCodes(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string
+		args  []string // after the subcommand; --folder is added
+		code  int
+		out   string // part of stdout, or of stderr for code 2
+	}{
+		{"renames", []string{"a.txt"}, []string{"--step", "prepend:x-"}, 0, "a.txt → x-a.txt"},
+		{"no steps leaves names unchanged", []string{"a.txt"}, nil, 0, ""},
+		{"unchanged is not a failure", []string{"a.txt", "b.txt"}, []string{"--step", "replace:a:c"}, 0, "a.txt → c.txt"},
+		{"target taken", []string{"a.txt", "b.txt"}, []string{"--step", "replace:a:b"}, 1, ""},
+		{"target taken, counter", []string{"a.txt", "b.txt"}, []string{"--step", "replace:a:b", "--on-conflict", "counter"}, 0, "b (2).txt"},
+		{"invalid name", []string{"a.txt"}, []string{"--step", "replace:a:con", "--profile", "windows"}, 1, "reserved filename"},
+		{"match any", []string{"a.txt", "b.md", "c.go"}, []string{"--filter", "ext:txt", "--filter", "ext:md", "--match", "any", "--step", "prepend:x-"}, 0, "b.md → x-b.md"},
+		{"match all", []string{"a.txt", "b.md"}, []string{"--filter", "ext:txt", "--filter", "ext:md", "--step", "prepend:x-"}, 0, "process 0 file(s)"},
+		{"bad match", nil, []string{"--match", "some"}, 2, "--match must be all or any"},
+		{"unknown flag", nil, []string{"--colour"}, 2, "-colour"},
+		{"stray argument", nil, []string{"extra"}, 2, "unexpected argument: extra"},
+		{"bad filter", nil, []string{"--filter", "nope:x"}, 2, "unknown mode"},
+		{"filter without value", nil, []string{"--filter", "contains"}, 2, "expected MODE:VALUE"},
+		{"bad step", nil, []string{"--step", "twist:x"}, 2, "unknown operation"},
+		{"bad numbering", nil, []string{"--step", "number:_:pad=lots"}, 2, "--step"},
+		{"bad conflict policy", nil, []string{"--on-conflict", "merge"}, 2, "--on-conflict"},
+		{"bad profile", nil, []string{"--profile", "amiga"}, 2, "--profile"},
+		{"bad case-fs", nil, []string{"--case-fs", "maybe"}, 2, "--case-fs"},
+		{"negative depth", nil, []string{"--max-depth", "-1"}, 2, "--max-depth"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := cliFolder(t, tc.files...)
+			var stdout, stderr bytes.Buffer
+			args := append([]string{"plan", "--folder", dir}, tc.args...)
+			code := runCLI(args, &stdout, &stderr)
+			if code != tc.code {
+				t.Fatalf("exit %d, want %d\nstdout: %s\nstderr: %s", code, tc.code, stdout.String(), stderr.String())
+			}
+			got := stdout.String()
+			if code == 2 {
+				got = stderr.String()
+			}
+			if !strings.Contains(got, tc.out) {
+				t.Errorf("output %q doesn't mention %q", got, tc.out)
+			}
+		})
+	}
+}
+
+func TestRunCLIMissingFolder(t *testing.T) {
+	for _, cmd := range []string{"plan", "apply", "recover"} {
+		var stdout, stderr bytes.Buffer
+		if code := runCLI([]string{cmd}, &stdout, &stderr); code != 2 || !strings.Contains(stderr.String(), "--folder is required") {
+			t.Errorf("%s: exit %d, stderr %q", cmd, code, stderr.String())
+		}
+	}
+	var stdout, stderr bytes.Buffer
+	if code := runCLI([]string{"undo"}, &stdout, &stderr); code != 2 || !strings.Contains(stderr.String(), "--log is required") {
+		t.Errorf("undo: exit %d, stderr %q", code, stderr.String())
+	}
+}
+
+func TestRunCLIApplyAndUndo(t *testing.T) {
+	dir := cliFolder(t, "a.txt", "b.txt")
+	log := filepath.Join(t.TempDir(), "undo.csv")
+	var stdout, stderr bytes.Buffer
+
+	// the dry run renames nothing
+	args := []string{"apply", "--folder", dir, "--step", "append:-1"}
+	if code := runCLI(args, &stdout, &stderr); code != 0 {
+		t.Fatalf("dry run: exit %d: %s", code, stderr.String())
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a.txt")); err != nil {
+		t.Fatalf("dry run renamed a.txt: %v", err)
+	}
+
+	args = append(args, "--dry-run=false", "--undo-log", log)
+	if code := runCLI(args, &stdout, &stderr); code != 0 {
+		t.Fatalf("apply: exit %d: %s", code, stderr.String())
+	}
+	for _, name := range []string{"a-1.txt", "b-1.txt"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("apply: %v", err)
+		}
+	}
+
+	args = []string{"undo", "--log", log, "--dry-run=false"}
+	if code := runCLI(args, &stdout, &stderr); code != 0 {
+		t.Fatalf("undo: exit %d: %s", code, stderr.String())
+	}
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("undo: %v", err)
+		}
+	}
+
+	// nothing left to undo: the files are back, the new names are gone
+	if code := runCLI(args, &stdout, &stderr); code != 1 {
+		t.Errorf("second undo: exit %d, want 1", code)
+	}
+}
+
+func TestRunCLIRecover(t *testing.T) {
+	dir := cliFolder(t, "a.txt")
+	var stdout, stderr bytes.Buffer
+	if code := runCLI([]string{"recover", "--folder", dir}, &stdout, &stderr); code != 0 || !strings.Contains(stdout.String(), "Nothing to recover") {
+		t.Errorf("exit %d, stdout %q", code, stdout.String())
+	}
+	if code := runCLI([]string{"recover", "--folder", dir, "--roll", "sideways"}, &stdout, &stderr); code != 2 {
+		t.Errorf("--roll sideways: exit %d, want 2", code)
+	}
+}
+
+func TestParseCLIFilter(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want engine.FilterRule
+		bad  bool
+	}{
+		{raw: "contains:IMG", want: engine.FilterRule{Mode: "contains", Value: "IMG"}},
+		{raw: "ext:jpg", want: engine.FilterRule{Mode: "extension", Value: "jpg"}},
+		{raw: "!contains:thumb", want: engine.FilterRule{Mode: "contains", Value: "thumb", Not: true}},
+		{raw: "regex:^a:b$", want: engine.FilterRule{Mode: "regex", Value: "^a:b$"}},
+		{raw: "mtime:last 7 days", want: engine.FilterRule{Mode: "modified", Value: "last 7 days"}},
+		{raw: "hidden", want: engine.FilterRule{Mode: "hidden"}},
+		{raw: "regex:(", bad: true},
+		{raw: "size:lots", bad: true},
+		{raw: "colour:red", bad: true},
+	}
+	for _, tc := range tests {
+		got, err := parseCLIFilter(tc.raw)
+		if tc.bad {
+			if err == nil {
+				t.Errorf("%q: got %+v, want an error", tc.raw, got)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("%q: got %+v, %v; want %+v", tc.raw, got, err, tc.want)
+		}
+	}
+}
+
+func TestParseCLIStep(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want func(engine.RenameStep) bool
+		bad  bool
+	}{
+		{raw: "replace:a:b", want: func(s engine.RenameStep) bool { return s.Op == engine.OpReplaceText && s.A == "a" && s.B == "b" }},
+		{raw: "prepend:a:b", want: func(s engine.RenameStep) bool { return s.Op == engine.OpPrepend && s.A == "a:b" }},
+		{raw: "regex:^img:pic", want: func(s engine.RenameStep) bool { return s.Op == engine.OpRegexReplace && !s.IgnoreCase }},
+		{raw: "iregex:^img:pic", want: func(s engine.RenameStep) bool { return s.Op == engine.OpRegexReplace && s.IgnoreCase && s.B == "pic" }},
+		{raw: "Append:x", want: func(s engine.RenameStep) bool { return s.Op == engine.OpAppend && s.A == "x" }},
+		{raw: "number:_:start=5,pad=auto,sort=size,per-folder", want: func(s engine.RenameStep) bool {
+			return s.A == "_" && s.Num.Start == 5 && s.Num.Padding == engine.PadAuto && s.Num.SortBy == engine.SortBySize && s.Num.PerFolder
+		}},
+		{raw: "case:snake:upper", want: func(s engine.RenameStep) bool {
+			return s.Case.Style == engine.CaseSnake && s.Case.Ext == engine.ExtUpper
+		}},
+		{raw: "unicode:ascii", want: func(s engine.RenameStep) bool { return s.A == engine.UnicodeTranslit }},
+		{raw: "sanitize:slug,remove", want: func(s engine.RenameStep) bool {
+			return s.Sanitize.Slug && s.Sanitize.Invalid == engine.SanitizeRemove && !s.Sanitize.URLDecode
+		}},
+		{raw: "sanitize", want: func(s engine.RenameStep) bool { return s.Sanitize == engine.DefaultSanitize() }},
+		{raw: "number:_:pos=middle", bad: true},
+		{raw: "case:wavy", bad: true},
+		{raw: "unicode:nfx", bad: true},
+		{raw: "sanitize:shiny", bad: true},
+		{raw: "regex:(", bad: true},
+		{raw: "template:{nope}", bad: true},
+	}
+	for _, tc := range tests {
+		got, err := parseCLIStep(tc.raw)
+		if tc.bad {
+			if err == nil {
+				t.Errorf("%q: got %+v, want an error", tc.raw, got)
+			}
+			continue
+		}
+		if err != nil || !tc.want(got) {
+			t.Errorf("%q: got %+v, %v", tc.raw, got, err)
+		}
+	}
+}
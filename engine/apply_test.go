@@ -0,0 +1,225 @@
+package engine
+
+import (
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFiles creates name → content files in dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// readDir returns name → content for the files in dir, temp files and
+// journals included.
+func readDir(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := map[string]string{}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		out[e.Name()] = string(data)
+	}
+	return out
+}
+
+func planItem(dir, from, to string) RenamePlanItem {
+	return RenamePlanItem{
+		OldPath: filepath.Join(dir, from),
+		NewPath: filepath.Join(dir, to),
+		OldName: from,
+		NewName: to,
+		Status:  StatusOK,
+	}
+}
+
+// failRename makes rename fail for moves onto the given base name.
+func failRename(t *testing.T, target string) {
+	t.Helper()
+	rename = func(from, to string) error {
+		if filepath.Base(to) == target {
+			return &os.LinkError{Op: "rename", Old: from, New: to, Err: errors.New("injected failure")}
+		}
+		return os.Rename(from, to)
+	}
+	t.Cleanup(func() { rename = os.Rename })
+}
+
+// stranded splits a listing into the named files and the contents left
+// under temp names.
+func stranded(files map[string]string) (named map[string]string, tmp []string, journals int) {
+	named = map[string]string{}
+	for name, content := range files {
+		switch {
+		case strings.HasPrefix(name, tmpPrefix):
+			tmp = append(tmp, content)
+		case strings.HasPrefix(name, journalPrefix):
+			journals++
+		default:
+			named[name] = content
+		}
+	}
+	return named, tmp, journals
+}
+
+func TestApplyChainsAndSwaps(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a": "A", "b": "B", "c": "C", "x": "X"})
+
+	// a ↔ b swap, and c → x → y chain
+	plan := []RenamePlanItem{
+		planItem(dir, "a", "b"),
+		planItem(dir, "b", "a"),
+		planItem(dir, "c", "x"),
+		planItem(dir, "x", "y"),
+	}
+	for _, it := range Apply(plan) {
+		if it.Status != StatusRenamed {
+			t.Errorf("%s → %s: %s (%s)", it.OldName, it.NewName, it.Status, it.Reason)
+		}
+	}
+	want := map[string]string{"a": "B", "b": "A", "x": "C", "y": "X"}
+	if got := readDir(t, dir); !maps.Equal(got, want) {
+		t.Errorf("folder = %v, want %v", got, want)
+	}
+}
+
+func TestApplyFailedChainKeepsFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"x": "XDATA", "y": "YDATA"})
+
+	// x → y goes ahead; y → missing/z fails in phase 2, and y's name is
+	// taken by then, so its file must stay under the temp name
+	plan := []RenamePlanItem{
+		planItem(dir, "x", "y"),
+		planItem(dir, "y", filepath.Join("missing", "z")),
+	}
+	out := Apply(plan)
+	if out[0].Status != StatusRenamed {
+		t.Errorf("x → y: %s (%s)", out[0].Status, out[0].Reason)
+	}
+	if out[1].Status != StatusError || !strings.Contains(out[1].Reason, tmpPrefix) {
+		t.Errorf("y → missing/z: %s (%s), want an error naming the temp file", out[1].Status, out[1].Reason)
+	}
+
+	named, tmp, journals := stranded(readDir(t, dir))
+	if named["y"] != "XDATA" || len(named) != 1 {
+		t.Errorf("named files = %v, want only y = XDATA", named)
+	}
+	if len(tmp) != 1 || tmp[0] != "YDATA" {
+		t.Errorf("temp files = %v, want YDATA kept", tmp)
+	}
+	if journals != 1 {
+		t.Errorf("%d journals, want the batch's kept", journals)
+	}
+
+	rec, err := FindRecovery(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.Journals) != 1 || len(rec.Journals[0].Stranded()) != 1 || len(rec.Orphans) != 0 {
+		t.Fatalf("FindRecovery = %+v, want one journal with one stranded file", rec)
+	}
+}
+
+func TestApplyFailedChainRestores(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"x": "XDATA", "y": "YDATA"})
+
+	// listed the other way round, y fails before x moves: y goes back, and
+	// x → y then finds it there and goes back too instead of replacing it
+	plan := []RenamePlanItem{
+		planItem(dir, "y", filepath.Join("missing", "z")),
+		planItem(dir, "x", "y"),
+	}
+	out := Apply(plan)
+	if out[0].Status != StatusError {
+		t.Errorf("y → missing/z: %s, want error", out[0].Status)
+	}
+	if out[1].Status != StatusError || out[1].Reason != ReasonTargetExists {
+		t.Errorf("x → y: %s (%s), want %q", out[1].Status, out[1].Reason, ReasonTargetExists)
+	}
+	want := map[string]string{"x": "XDATA", "y": "YDATA"}
+	if got := readDir(t, dir); !maps.Equal(got, want) {
+		t.Errorf("folder = %v, want %v and no journal", got, want)
+	}
+}
+
+func TestApplyFailedSwapRecovers(t *testing.T) {
+	for _, forward := range []bool{true, false} {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{"a": "A", "b": "B"})
+		failRename(t, "a") // b's phase-2 move onto a fails
+
+		out := Apply([]RenamePlanItem{planItem(dir, "a", "b"), planItem(dir, "b", "a")})
+		if out[0].Status != StatusRenamed || out[1].Status != StatusError {
+			t.Fatalf("statuses = %s, %s; want renamed, error", out[0].Status, out[1].Status)
+		}
+		if !strings.Contains(out[1].Reason, "b is taken") {
+			t.Errorf("reason = %q, want it to say b is taken", out[1].Reason)
+		}
+		named, tmp, _ := stranded(readDir(t, dir))
+		if named["b"] != "A" || len(tmp) != 1 || tmp[0] != "B" {
+			t.Fatalf("after failed swap: named %v, temp %v", named, tmp)
+		}
+
+		rename = os.Rename
+		rec, err := FindRecovery(dir, false)
+		if err != nil || len(rec.Journals) != 1 {
+			t.Fatalf("FindRecovery = %+v, %v", rec, err)
+		}
+		results, err := rec.Journals[0].Recover(forward)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, it := range results {
+			if it.Status == StatusError {
+				t.Errorf("recover: %s", it.Reason)
+			}
+		}
+		want := map[string]string{"a": "B", "b": "A"}
+		if !forward {
+			want = map[string]string{"a": "A", "b": "B"}
+		}
+		if got := readDir(t, dir); !maps.Equal(got, want) {
+			t.Errorf("forward=%v: folder = %v, want %v", forward, got, want)
+		}
+	}
+}
+
+func TestApplyBackup(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"new.txt": "NEW", "old.txt": "OLD"})
+
+	it := planItem(dir, "new.txt", "old.txt")
+	it.BackupPath = filepath.Join(dir, "old (backup).txt")
+	if out := Apply([]RenamePlanItem{it}); out[0].Status != StatusRenamed {
+		t.Fatalf("status %s (%s)", out[0].Status, out[0].Reason)
+	}
+	want := map[string]string{"old.txt": "NEW", "old (backup).txt": "OLD"}
+	if got := readDir(t, dir); !maps.Equal(got, want) {
+		t.Errorf("folder = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package engine
+
+import "os"
+
+// hiddenAttr is false outside Windows, where only dot-files are hidden.
+func hiddenAttr(os.FileInfo) bool {
+	return false
+}
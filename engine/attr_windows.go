@@ -0,0 +1,17 @@
+package engine
+
+import (
+	"os"
+	"syscall"
+)
+
+// hiddenAttr reports the Windows hidden attribute.
+func hiddenAttr(fi os.FileInfo) bool {
+	if fi == nil {
+		return false
+	}
+	if d, ok := fi.Sys().(*syscall.Win32FileAttributeData); ok {
+		return d.FileAttributes&syscall.FILE_ATTRIBUTE_HIDDEN != 0
+	}
+	return false
+}
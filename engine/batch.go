@@ -0,0 +1,215 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+/* -------------------- Batch -------------------- */
+
+// Batch holds the context some steps need from the whole set of files being
+// renamed, such as sequence numbers. Build one per selection with NewBatch
+// and ask it for each file's new name. Names are worked out once per file,
+// so a Batch can be kept for as long as its steps and files stay the same;
+// it is safe to use from several goroutines.
+type Batch struct {
+	steps []RenameStep
+	ctx   stepContext
+
+	mu    sync.Mutex
+	names map[string]batchName
+}
+
+// batchName is a file's new name and which steps changed it on the way.
+type batchName struct {
+	name    string
+	changed []bool
+}
+
+// stepContext is what applySteps knows about the file being renamed beyond
+// its name. The zero value renames without any batch context.
+type stepContext struct {
+	path string
+	// counters[i] holds the numbers handed out by steps[i]; nil for steps
+	// that don't number files
+	counters []map[string]counter
+	// file looks up (and caches) what is known of a file: its scan entry,
+	// or what the disk says; nil outside a batch
+	file func(string) File
+	// profile is the naming profile sanitising steps clean up for; "" is
+	// the host OS
+	profile string
+}
+
+// stat is the info of the file being renamed; nil when it can't be read.
+func (c stepContext) stat() os.FileInfo {
+	return c.lookup().Info
+}
+
+// created is the birth time of the file being renamed (see File.Created),
+// read from disk when the file wasn't scanned.
+func (c stepContext) created() time.Time {
+	f := c.lookup()
+	if f.Created.IsZero() && f.Info != nil {
+		return createdTime(f.Path, f.Info)
+	}
+	return f.Created
+}
+
+func (c stepContext) lookup() File {
+	if c.path == "" {
+		return File{}
+	}
+	if c.file == nil {
+		return statFile(c.path)
+	}
+	return c.file(c.path)
+}
+
+// statFile reads path's info from disk, following symlinks like a scan.
+// The birth time is left for created to read if a step asks for it.
+func statFile(path string) File {
+	f := File{Path: path}
+	if fi, err := os.Stat(path); err == nil {
+		f.Info = fi
+	}
+	return f
+}
+
+// NewBatch prepares the steps for files that will live on a filesystem
+// following profile. File info comes from listing, the scan the files were
+// picked from, and is only read from disk for files missing from it, and
+// only when a step needs it (e.g. numbering sorted by size, or a {size}
+// template token).
+func NewBatch(files []string, listing []File, steps []RenameStep, profile string) *Batch {
+	b := &Batch{steps: steps, names: map[string]batchName{}}
+	b.ctx.profile = profile
+
+	known := make(map[string]File, len(listing))
+	for _, f := range listing {
+		known[f.Path] = f
+	}
+	file := func(p string) File {
+		if f, ok := known[p]; ok {
+			return f
+		}
+		f := statFile(p)
+		known[p] = f
+		return f
+	}
+	info := func(p string) os.FileInfo { return file(p).Info }
+
+	b.ctx.file = file
+
+	for i, s := range steps {
+		var num NumberingOptions
+		switch s.Op {
+		case OpNumber:
+			num = s.Num
+		case OpTemplate:
+			parts, err := parseTemplate(s.A)
+			if err != nil || !templateUsesCounter(parts) {
+				continue
+			}
+			num = NumberingOptions{Start: 1, Increment: 1, SortBy: SortByNatural}
+		default:
+			continue
+		}
+		if b.ctx.counters == nil {
+			b.ctx.counters = make([]map[string]counter, len(steps))
+		}
+		b.ctx.counters[i] = assignCounters(files, num, info)
+	}
+	return b
+}
+
+// Name returns the new base name for path. Files that were not part of the
+// batch get no sequence number: numbering steps leave them alone and {n}
+// expands to nothing.
+func (b *Batch) Name(path string) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.lookup(path).name
+}
+
+// lookup works out path's new name the first time it is asked for; b.mu
+// must be held.
+func (b *Batch) lookup(path string) batchName {
+	if n, ok := b.names[path]; ok {
+		return n
+	}
+	ctx := b.ctx
+	ctx.path = path
+	n := batchName{changed: make([]bool, len(b.steps))}
+	n.name = applySteps(filepath.Base(path), b.steps, ctx, n.changed)
+	b.names[path] = n
+	return n
+}
+
+// NoopSteps reports, for each step, whether it leaves the names of all of
+// files as they were. It goes by what the steps did while working out the
+// names, so it costs nothing for files already named. With no files there
+// is nothing to judge by and no step is reported.
+func (b *Batch) NoopSteps(files []string) []bool {
+	noop := make([]bool, len(b.steps))
+	if len(files) == 0 {
+		return noop
+	}
+	for i := range noop {
+		noop[i] = true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	left := len(b.steps)
+	for _, p := range files {
+		for i, changed := range b.lookup(p).changed {
+			if changed && noop[i] {
+				noop[i] = false
+				left--
+			}
+		}
+		if left == 0 {
+			break
+		}
+	}
+	return noop
+}
+
+// Trace returns the name of path after each step in turn, so a surprising
+// result can be pinned on the step that caused it. Unlike Name it keeps any
+// spaces the steps leave at the ends.
+func (b *Batch) Trace(path string) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ctx := b.ctx
+	ctx.path = path
+	names := make([]string, len(b.steps))
+	name := filepath.Base(path)
+	for i, s := range b.steps {
+		name = applyStep(name, i, s, ctx)
+		names[i] = name
+	}
+	return names
+}
+
+// Numbered reports whether a step hands out sequence numbers, which makes
+// every name depend on the whole set of files the batch was built for.
+func (b *Batch) Numbered() bool {
+	return b.ctx.counters != nil
+}
+
+func modTime(fi os.FileInfo) time.Time {
+	if fi == nil {
+		return time.Time{}
+	}
+	return fi.ModTime()
+}
+
+func fileSize(fi os.FileInfo) int64 {
+	if fi == nil {
+		return 0
+	}
+	return fi.Size()
+}
@@ -0,0 +1,54 @@
+package engine
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestBatchNoopSteps(t *testing.T) {
+	steps := []RenameStep{
+		{Op: OpReplaceText, A: "IMG", B: "Photo"},
+		{Op: OpReplaceText, A: "zzz", B: "y"}, // matches nothing
+		{Op: OpAppend, A: " "},                // trimmed away, but changed the name on the way
+		{Op: OpRemoveText, A: "Photo"},        // only finds what the first step put in
+	}
+	tests := []struct {
+		files []string
+		want  []bool
+	}{
+		{nil, []bool{false, false, false, false}},
+		{[]string{"/d/IMG_1.jpg"}, []bool{false, true, false, false}},
+		{[]string{"/d/a.jpg"}, []bool{true, true, false, true}},
+		{[]string{"/d/a.jpg", "/d/IMG_1.jpg"}, []bool{false, true, false, false}},
+	}
+	for _, tc := range tests {
+		b := NewBatch(tc.files, nil, steps, ProfileLinux)
+		if len(tc.files) > 0 {
+			b.Name(tc.files[0]) // named before and after asking must agree
+		}
+		if got := b.NoopSteps(tc.files); !slices.Equal(got, tc.want) {
+			t.Errorf("%v: NoopSteps = %v, want %v", tc.files, got, tc.want)
+		}
+	}
+}
+
+func TestBatchUsesListing(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.txt": "12345", "b.txt": "1234567", "c.txt": "1"})
+	a, b, c := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt"), filepath.Join(dir, "c.txt")
+
+	// the scan saw other sizes than the disk has now, and missed c.txt
+	scan := listing(dir, map[string]int64{"a.txt": 900, "b.txt": 100})
+	steps := []RenameStep{
+		{Op: OpNumber, A: "_", Num: NumberingOptions{Start: 1, Increment: 1, Position: NumPrepend, SortBy: SortBySize}},
+		{Op: OpTemplate, A: "{name}_{size}{ext}"},
+	}
+	batch := NewBatch([]string{a, b, c}, scan, steps, ProfileLinux)
+	want := map[string]string{a: "3_a_900.txt", b: "2_b_100.txt", c: "1_c_1.txt"}
+	for p, name := range want {
+		if got := batch.Name(p); got != name {
+			t.Errorf("%s → %s, want %s", filepath.Base(p), got, name)
+		}
+	}
+}
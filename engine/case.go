@@ -0,0 +1,196 @@
+package engine
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+/* -------------------- Case transformation -------------------- */
+
+const (
+	CaseUpper    = "UPPER"
+	CaseLower    = "lower"
+	CaseTitle    = "Title Case"
+	CaseSentence = "Sentence case"
+	CaseCamel    = "camelCase"
+	CaseSnake    = "snake_case"
+	CaseKebab    = "kebab-case"
+)
+
+// CaseStyles lists the supported name styles in UI order.
+var CaseStyles = []string{CaseUpper, CaseLower, CaseTitle, CaseSentence, CaseCamel, CaseSnake, CaseKebab}
+
+const (
+	ExtKeep  = "keep extension"
+	ExtLower = "lower extension"
+	ExtUpper = "UPPER extension"
+)
+
+// ExtCaseStyles lists the extension options in UI order.
+var ExtCaseStyles = []string{ExtKeep, ExtLower, ExtUpper}
+
+// DefaultSmallWords stay lower case in Title Case unless first or last.
+const DefaultSmallWords = "a, an, and, as, at, but, by, for, in, nor, of, on, or, the, to, vs, via"
+
+type CaseOptions struct {
+	Style      string `json:"style"`                 // one of CaseStyles
+	Ext        string `json:"ext,omitempty"`         // one of ExtCaseStyles; "" keeps the extension
+	SmallWords string `json:"small_words,omitempty"` // comma or space separated, Title Case only
+}
+
+func DefaultCase() CaseOptions {
+	return CaseOptions{Style: CaseLower, Ext: ExtKeep, SmallWords: DefaultSmallWords}
+}
+
+// applyCase converts the base name to opts.Style and the extension to
+// opts.Ext. Casing is Unicode aware (e.g. "straße" → "STRASSE").
+func applyCase(name string, opts CaseOptions) string {
+	base, ext := splitExt(name)
+	// the dot of a dot-file (".env", ".env.local") is not part of a word;
+	// keep it, or camel, snake and kebab case would un-hide the file
+	dot := ""
+	if strings.HasPrefix(base, ".") {
+		dot, base = ".", base[1:]
+	}
+
+	switch opts.Style {
+	case CaseUpper:
+		base = cases.Upper(language.Und).String(base)
+	case CaseLower:
+		base = cases.Lower(language.Und).String(base)
+	case CaseTitle:
+		base = titleCase(base, smallWordSet(opts.SmallWords))
+	case CaseSentence:
+		base = capitalize(cases.Lower(language.Und).String(base))
+	case CaseCamel:
+		words := splitWords(base)
+		for i, w := range words {
+			if i == 0 {
+				words[i] = cases.Lower(language.Und).String(w)
+			} else {
+				words[i] = capitalize(cases.Lower(language.Und).String(w))
+			}
+		}
+		base = strings.Join(words, "")
+	case CaseSnake:
+		base = cases.Lower(language.Und).String(strings.Join(splitWords(base), "_"))
+	case CaseKebab:
+		base = cases.Lower(language.Und).String(strings.Join(splitWords(base), "-"))
+	}
+
+	switch opts.Ext {
+	case ExtLower:
+		ext = cases.Lower(language.Und).String(ext)
+	case ExtUpper:
+		ext = cases.Upper(language.Und).String(ext)
+	}
+	return dot + base + ext
+}
+
+// titleCase capitalises each word, keeping separators as they are and small
+// words lower case unless they start or end the name.
+func titleCase(s string, small map[string]bool) string {
+	lower := cases.Lower(language.Und)
+	type span struct{ start, end int }
+
+	var words []span
+	start := -1
+	runes := []rune(s)
+	j := 0 // index of the rune at byte offset i
+	for i := range s {
+		if inWord(runes, j) {
+			if start < 0 {
+				start = i
+			}
+		} else if start >= 0 {
+			words = append(words, span{start, i})
+			start = -1
+		}
+		j++
+	}
+	if start >= 0 {
+		words = append(words, span{start, len(s)})
+	}
+
+	var b strings.Builder
+	prev := 0
+	for i, w := range words {
+		b.WriteString(s[prev:w.start])
+		word := lower.String(s[w.start:w.end])
+		if i == 0 || i == len(words)-1 || !small[word] {
+			word = capitalize(word)
+		}
+		b.WriteString(word)
+		prev = w.end
+	}
+	b.WriteString(s[prev:])
+	return b.String()
+}
+
+// splitWords breaks a name into words on separators and case changes:
+// "myHTTPServer v2-final" → my, HTTP, Server, v2, final.
+func splitWords(s string) []string {
+	var words []string
+	var cur []rune
+	flush := func() {
+		if len(cur) > 0 {
+			words = append(words, string(cur))
+			cur = nil
+		}
+	}
+
+	runes := []rune(s)
+	for i, r := range runes {
+		if !inWord(runes, i) {
+			flush()
+			continue
+		}
+		if len(cur) > 0 && unicode.IsUpper(r) {
+			prev := cur[len(cur)-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			// lower→Upper ("myHTTP") or the last capital of an acronym
+			// before a lower-case run ("HTTPServer")
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				flush()
+			}
+		}
+		cur = append(cur, r)
+	}
+	flush()
+	return words
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+}
+
+// inWord reports whether runes[i] is part of a word. An apostrophe between
+// two letters is ("don't", "it’s"), so contractions stay one word.
+func inWord(runes []rune, i int) bool {
+	r := runes[i]
+	if r == '\'' || r == '’' {
+		return i > 0 && i+1 < len(runes) && unicode.IsLetter(runes[i-1]) && unicode.IsLetter(runes[i+1])
+	}
+	return isWordRune(r)
+}
+
+// capitalize title-cases the first letter and leaves the rest alone.
+func capitalize(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError {
+		return s
+	}
+	return string(unicode.ToTitle(r)) + s[size:]
+}
+
+func smallWordSet(list string) map[string]bool {
+	set := map[string]bool{}
+	for _, w := range strings.FieldsFunc(list, func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) {
+		set[cases.Lower(language.Und).String(w)] = true
+	}
+	return set
+}
@@ -0,0 +1,99 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestApplyCase(t *testing.T) {
+	tests := []struct {
+		name  string
+		style string
+		ext   string
+		want  string
+	}{
+		{"My Holiday Photo.JPG", CaseLower, ExtLower, "my holiday photo.jpg"},
+		{"my holiday photo.jpg", CaseUpper, ExtKeep, "MY HOLIDAY PHOTO.jpg"},
+		{"the lord of the rings.txt", CaseTitle, ExtKeep, "The Lord of the Rings.txt"},
+		{"HELLO WORLD.txt", CaseSentence, ExtKeep, "Hello world.txt"},
+		{"my holiday-photo.txt", CaseCamel, ExtKeep, "myHolidayPhoto.txt"},
+		{"MyHolidayPhoto.txt", CaseSnake, ExtKeep, "my_holiday_photo.txt"},
+		{"My Holiday_Photo.txt", CaseKebab, ExtKeep, "my-holiday-photo.txt"},
+		{"straße.txt", CaseUpper, ExtKeep, "STRASSE.txt"},
+
+		// apostrophes between letters belong to the word
+		{"don't stop.txt", CaseTitle, ExtKeep, "Don't Stop.txt"},
+		{"IT’S A SIGN.txt", CaseTitle, ExtKeep, "It’s a Sign.txt"},
+		{"rock 'n' roll.mp3", CaseTitle, ExtKeep, "Rock 'N' Roll.mp3"},
+		{"o'neil's song.mp3", CaseSentence, ExtKeep, "O'neil's song.mp3"},
+		{"don't stop.txt", CaseSnake, ExtKeep, "don't_stop.txt"},
+		{"Don't Stop.txt", CaseCamel, ExtKeep, "don'tStop.txt"},
+		{"don't stop.txt", CaseKebab, ExtKeep, "don't-stop.txt"},
+
+		// dot-files stay hidden whatever the style
+		{".env", CaseCamel, ExtKeep, ".env"},
+		{".gitignore", CaseSnake, ExtKeep, ".gitignore"},
+		{".Git Ignore", CaseKebab, ExtKeep, ".git-ignore"},
+		{".env.Local", CaseUpper, ExtLower, ".ENV.local"},
+		{".my env.local", CaseSnake, ExtKeep, ".my_env.local"},
+	}
+	for _, tt := range tests {
+		got := applyCase(tt.name, CaseOptions{Style: tt.style, Ext: tt.ext, SmallWords: DefaultSmallWords})
+		if got != tt.want {
+			t.Errorf("applyCase(%q, %s, %s) = %q, want %q", tt.name, tt.style, tt.ext, got, tt.want)
+		}
+	}
+}
+
+func TestCaseStyles(t *testing.T) {
+	const name = "my HTTPServer v2-final.Txt"
+	want := map[string]string{
+		CaseUpper:    "MY HTTPSERVER V2-FINAL.Txt",
+		CaseLower:    "my httpserver v2-final.Txt",
+		CaseTitle:    "My Httpserver V2-Final.Txt",
+		CaseSentence: "My httpserver v2-final.Txt",
+		CaseCamel:    "myHttpServerV2Final.Txt",
+		CaseSnake:    "my_http_server_v2_final.Txt",
+		CaseKebab:    "my-http-server-v2-final.Txt",
+	}
+	for _, style := range CaseStyles {
+		if got := applyCase(name, CaseOptions{Style: style, Ext: ExtKeep}); got != want[style] {
+			t.Errorf("%s: %q, want %q", style, got, want[style])
+		}
+	}
+}
+
+func TestTitleCaseSmallWords(t *testing.T) {
+	tests := []struct {
+		in, small, want string
+	}{
+		{"the lord of the rings", DefaultSmallWords, "The Lord of the Rings"},
+		{"a tale of two cities", DefaultSmallWords, "A Tale of Two Cities"},
+		{"what it is for", DefaultSmallWords, "What It Is For"},
+		{"war and peace", "", "War And Peace"},
+		{"war and peace", "AND, Peace", "War and Peace"},
+		{"one_two-three", "two", "One_two-Three"},
+	}
+	for _, tc := range tests {
+		if got := titleCase(tc.in, smallWordSet(tc.small)); got != tc.want {
+			t.Errorf("titleCase(%q, %q) = %q, want %q", tc.in, tc.small, got, tc.want)
+		}
+	}
+}
+
+func TestSplitWords(t *testing.T) {
+	tests := map[string][]string{
+		"myHTTPServer v2-final": {"my", "HTTP", "Server", "v2", "final"},
+		"snake_case_name":       {"snake", "case", "name"},
+		"don't stop":            {"don't", "stop"},
+		"'quoted'":              {"quoted"},
+		"Ünïcode Wörds":         {"Ünïcode", "Wörds"},
+		"":                      nil,
+	}
+	for in, want := range tests {
+		got := splitWords(in)
+		if strings.Join(got, "|") != strings.Join(want, "|") {
+			t.Errorf("splitWords(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
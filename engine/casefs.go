@@ -0,0 +1,214 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+)
+
+/* -------------------- Case-insensitive folders -------------------- */
+
+// On macOS and Windows volumes "Report.PDF" and "report.pdf" are the same
+// file, so planning has to compare names the way the folder does.
+const (
+	CaseFSAuto        = "Detect case sensitivity"
+	CaseFSSensitive   = "Case-sensitive"
+	CaseFSInsensitive = "Case-insensitive"
+)
+
+// CaseFSModes lists the modes in UI order; "" means CaseFSAuto.
+var CaseFSModes = []string{CaseFSAuto, CaseFSSensitive, CaseFSInsensitive}
+
+// pathKeys compares paths the way their folders do: of gives the key two
+// paths share when they name the same file, exists checks the disk with
+// the same rules. Folders are probed (auto mode) and listed once each.
+// Given a scan, exists, info and names look there instead of the disk (see
+// Options.Listing).
+type pathKeys struct {
+	mode     string
+	fold     cases.Caser
+	probed   map[string]bool
+	listings map[string]map[string][]string // dir -> folded name -> names
+
+	scan   []File
+	listed map[string][]string    // key (of) -> scanned paths; built on first use
+	infos  map[string]os.FileInfo // path -> scanned info; built with dirs
+	dirs   map[string][]string    // dir -> scanned names; built on first use
+}
+
+func newPathKeys(mode string, scan []File) *pathKeys {
+	return &pathKeys{
+		mode:     mode,
+		fold:     cases.Fold(),
+		probed:   map[string]bool{},
+		listings: map[string]map[string][]string{},
+		scan:     scan,
+	}
+}
+
+// scanned reports whether the scan lists a file other than oldPath at path,
+// comparing names by the folder's case rules.
+func (k *pathKeys) scanned(oldPath, path string) bool {
+	if k.listed == nil {
+		k.listed = make(map[string][]string, len(k.scan))
+		for _, f := range k.scan {
+			key := k.of(f.Path)
+			k.listed[key] = append(k.listed[key], filepath.Clean(f.Path))
+		}
+	}
+	for _, other := range k.listed[k.of(path)] {
+		if other != filepath.Clean(oldPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// index builds infos and dirs from the scan.
+func (k *pathKeys) index() {
+	if k.dirs != nil {
+		return
+	}
+	k.infos = make(map[string]os.FileInfo, len(k.scan))
+	k.dirs = map[string][]string{}
+	for _, f := range k.scan {
+		p := filepath.Clean(f.Path)
+		k.infos[p] = f.Info
+		dir := filepath.Dir(p)
+		k.dirs[dir] = append(k.dirs[dir], filepath.Base(p))
+	}
+}
+
+// info is the file info of path, from the scan when there is one; nil when
+// it can't be had.
+func (k *pathKeys) info(path string) os.FileInfo {
+	if k.scan != nil {
+		k.index()
+		return k.infos[filepath.Clean(path)]
+	}
+	fi, _ := os.Stat(path)
+	return fi
+}
+
+// names lists the names in dir, from the scan when there is one.
+func (k *pathKeys) names(dir string) []string {
+	if k.scan != nil {
+		k.index()
+		return k.dirs[filepath.Clean(dir)]
+	}
+	entries, _ := os.ReadDir(dir)
+	names := make([]string, len(entries))
+	for i, e := range entries {
+		names[i] = e.Name()
+	}
+	return names
+}
+
+func (k *pathKeys) insensitive(dir string) bool {
+	switch k.mode {
+	case CaseFSSensitive:
+		return false
+	case CaseFSInsensitive:
+		return true
+	}
+	v, ok := k.probed[dir]
+	if !ok {
+		v = CaseInsensitiveDir(dir)
+		k.probed[dir] = v
+	}
+	return v
+}
+
+func (k *pathKeys) of(path string) string {
+	path = filepath.Clean(path)
+	dir, name := filepath.Split(path)
+	if k.insensitive(dir) {
+		return dir + k.fold.String(name)
+	}
+	return path
+}
+
+// exists is TargetExists, also matching names that differ only in case when
+// the folder is treated as case-insensitive but the disk isn't (the user
+// picked it, e.g. for files headed to a Windows share).
+func (k *pathKeys) exists(oldPath, path string) bool {
+	if k.scan != nil {
+		return k.scanned(oldPath, path)
+	}
+	if TargetExists(oldPath, path) {
+		return true
+	}
+	path = filepath.Clean(path)
+	dir, name := filepath.Split(path)
+	if !k.insensitive(dir) {
+		return false
+	}
+	listing, ok := k.listings[dir]
+	if !ok {
+		listing = map[string][]string{}
+		entries, _ := os.ReadDir(filepath.Clean(dir))
+		for _, e := range entries {
+			folded := k.fold.String(e.Name())
+			listing[folded] = append(listing[folded], e.Name())
+		}
+		k.listings[dir] = listing
+	}
+	for _, other := range listing[k.fold.String(name)] {
+		if other := dir + other; other != filepath.Clean(oldPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// CaseInsensitiveDir reports whether names in dir are matched without regard
+// to case. It looks up an entry of dir (or dir itself) under a different
+// case; when nothing in reach has a letter, it goes by the host OS.
+func CaseInsensitiveDir(dir string) bool {
+	if dir == "" {
+		dir = "."
+	}
+	if f, err := os.Open(dir); err == nil {
+		names, _ := f.Readdirnames(64)
+		f.Close()
+		for _, name := range names {
+			if v, ok := probeCase(filepath.Join(dir, name)); ok {
+				return v
+			}
+		}
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		if v, ok := probeCase(abs); ok {
+			return v
+		}
+	}
+	return runtime.GOOS == "darwin" || runtime.GOOS == "windows" || runtime.GOOS == "ios"
+}
+
+// probeCase looks path up with the case of its last element swapped; ok is
+// false when the name has no letter to swap.
+func probeCase(path string) (insensitive, ok bool) {
+	dir, name := filepath.Split(path)
+	swapped := strings.Map(func(r rune) rune {
+		if unicode.IsUpper(r) {
+			return unicode.ToLower(r)
+		}
+		return unicode.ToUpper(r)
+	}, name)
+	if swapped == name {
+		return false, false
+	}
+	orig, err := os.Lstat(path)
+	if err != nil {
+		return false, false
+	}
+	other, err := os.Lstat(dir + swapped)
+	if err != nil {
+		return false, true
+	}
+	return os.SameFile(orig, other), true
+}
@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// insensitiveTempDir reports whether the test's temp folders match names
+// without regard to case, found without probeCase.
+func insensitiveTempDir(t *testing.T) bool {
+	t.Helper()
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"probe": ""})
+	_, err := os.Stat(filepath.Join(dir, "PROBE"))
+	return err == nil
+}
+
+func TestProbeCase(t *testing.T) {
+	insensitive := insensitiveTempDir(t)
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"Report.pdf": "", "2024-06.txt": ""})
+
+	tests := []struct {
+		name            string
+		insensitive, ok bool
+	}{
+		{"Report.pdf", insensitive, true},
+		{"report.PDF", insensitive, insensitive}, // only there when case is ignored
+		{"2024-06.txt", insensitive, true},       // "txt" has letters to swap
+		{"2024-06", false, false},                // no letter
+		{"missing.txt", false, false},
+	}
+	for _, tc := range tests {
+		v, ok := probeCase(filepath.Join(dir, tc.name))
+		if v != tc.insensitive || ok != tc.ok {
+			t.Errorf("probeCase(%s) = %v, %v; want %v, %v", tc.name, v, ok, tc.insensitive, tc.ok)
+		}
+	}
+
+	if !insensitive {
+		// two files that differ only in case are two files
+		writeFiles(t, dir, map[string]string{"REPORT.PDF": "other"})
+		if v, ok := probeCase(filepath.Join(dir, "Report.pdf")); v || !ok {
+			t.Errorf("probeCase with REPORT.PDF beside it = %v, %v; want false, true", v, ok)
+		}
+	}
+}
+
+func TestCaseInsensitiveDir(t *testing.T) {
+	insensitive := insensitiveTempDir(t)
+	host := runtime.GOOS == "darwin" || runtime.GOOS == "windows" || runtime.GOOS == "ios"
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"123": "", "456.7": "", "notes.txt": ""})
+	if got := CaseInsensitiveDir(dir); got != insensitive {
+		t.Errorf("with a lettered file: %v, want %v", got, insensitive)
+	}
+
+	// with no letter in the folder's name or its files' names there is
+	// nothing to probe
+	dir = filepath.Join(t.TempDir(), "2024")
+	writeFiles(t, dir, map[string]string{"123": "", "456.7": ""})
+	if got := CaseInsensitiveDir(dir); got != host {
+		t.Errorf("nothing to probe: %v, want the host default %v", got, host)
+	}
+}
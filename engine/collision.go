@@ -0,0 +1,260 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+/* -------------------- Collision policies -------------------- */
+
+// What Plan does when a target is taken, either by a file that stays on disk
+// or by another item of the batch.
+const (
+	CollisionSkip        = "Skip"
+	CollisionCounter     = "Add counter (2)"
+	CollisionUnderscore  = "Add counter _2"
+	CollisionOverwrite   = "Overwrite (backup existing)"
+	CollisionKeepNewer   = "Keep newer"
+	CollisionKeepLarger  = "Keep larger"
+	maxCollisionAttempts = 10000
+)
+
+// ReasonCollision starts the Reason of every item the collision policy
+// renamed, backed up for, or skipped in favour of another file.
+const ReasonCollision = "collision: "
+
+// CollisionPolicies lists the policies in UI order; "" means CollisionSkip.
+var CollisionPolicies = []string{
+	CollisionSkip,
+	CollisionCounter,
+	CollisionUnderscore,
+	CollisionOverwrite,
+	CollisionKeepNewer,
+	CollisionKeepLarger,
+}
+
+// resolveCounters gives every "ok" item whose target is taken the first free
+// "name (2).ext" / "name_2.ext". Items keep their name in plan order, so the
+// first of a set of duplicates wins.
+func resolveCounters(items []RenamePlanItem, onDisk []bool, sep string, keys *pathKeys) {
+	vacated := vacatedPaths(items, keys)
+	claimed := map[string]bool{}
+	taken := func(it *RenamePlanItem, path string) bool {
+		k := keys.of(path)
+		return claimed[k] || (!vacated[k] && keys.exists(it.OldPath, path))
+	}
+
+	for i := range items {
+		it := &items[i]
+		if it.Status != StatusOK {
+			continue
+		}
+		k := keys.of(it.NewPath)
+		if !claimed[k] && (!onDisk[i] || vacated[k]) {
+			claimed[k] = true
+			continue
+		}
+
+		wanted := it.NewName
+		for n := 2; n < maxCollisionAttempts; n++ {
+			name := counterName(wanted, sep, n)
+			path := filepath.Join(filepath.Dir(it.NewPath), name)
+			if taken(it, path) {
+				continue
+			}
+			it.NewName = name
+			it.NewPath = path
+			it.Reason = fmt.Sprintf("%scounter added (wanted %s)", ReasonCollision, wanted)
+			claimed[keys.of(path)] = true
+			break
+		}
+		if it.NewName == wanted {
+			it.Status = StatusSkip
+			it.Reason = ReasonDuplicate
+		}
+	}
+}
+
+// counterName inserts sep+n before the extension: "a.txt" → "a (2).txt".
+func counterName(name, sep string, n int) string {
+	ext := filepath.Ext(name)
+	if ext == name {
+		ext = "" // dotfile: ".env" → ".env (2)"
+	}
+	base := strings.TrimSuffix(name, ext)
+	if sep == " " {
+		return fmt.Sprintf("%s (%d)%s", base, n, ext)
+	}
+	return fmt.Sprintf("%s%s%d%s", base, sep, n, ext)
+}
+
+// resolveContests groups the "ok" items by target and settles each group
+// that collides with a file staying on disk or with another item, according
+// to policy. A skipped item leaves its source in place, which can make a new
+// contest, so it repeats until nothing changes. It returns which items need
+// the existing file at their target backed up.
+func resolveContests(items []RenamePlanItem, onDisk []bool, policy string, keys *pathKeys) []bool {
+	backup := make([]bool, len(items))
+	skip := func(i int, reason string) {
+		items[i].Status = StatusSkip
+		items[i].Reason = reason
+	}
+
+	for changed := true; changed; {
+		changed = false
+		vacated := vacatedPaths(items, keys)
+
+		groups := map[string][]int{}
+		var order []string
+		for i, it := range items {
+			if it.Status != StatusOK {
+				continue
+			}
+			k := keys.of(it.NewPath)
+			if groups[k] == nil {
+				order = append(order, k)
+			}
+			groups[k] = append(groups[k], i)
+		}
+
+		for _, k := range order {
+			g := groups[k]
+			existing := false
+			for _, i := range g {
+				backup[i] = false
+				existing = existing || (onDisk[i] && !vacated[k])
+			}
+			if len(g) == 1 && !existing {
+				continue
+			}
+
+			switch policy {
+			case CollisionKeepNewer, CollisionKeepLarger:
+				winner, label := pickWinner(items, g, items[g[0]].NewPath, existing, policy, keys)
+				for _, i := range g {
+					if i != winner {
+						skip(i, ReasonCollision+label)
+						changed = true
+					}
+				}
+				if winner >= 0 && existing {
+					backup[winner] = true
+				}
+			case CollisionOverwrite:
+				if len(g) > 1 {
+					for _, i := range g {
+						skip(i, ReasonDuplicate)
+					}
+					changed = true
+				} else {
+					backup[g[0]] = true
+				}
+			default:
+				for _, i := range g {
+					if len(g) > 1 {
+						skip(i, ReasonDuplicate)
+					} else {
+						skip(i, ReasonTargetExists)
+					}
+				}
+				changed = true
+			}
+		}
+	}
+	return backup
+}
+
+// pickWinner returns the item of g that keeps the target (-1 for the file
+// already there) and the reason the others are skipped. Ties go to the
+// file already on disk, then to the first item.
+func pickWinner(items []RenamePlanItem, g []int, target string, existing bool, policy string, keys *pathKeys) (int, string) {
+	better := func(a, b os.FileInfo) bool {
+		if policy == CollisionKeepLarger {
+			return fileSize(a) > fileSize(b)
+		}
+		return modTime(a).After(modTime(b))
+	}
+
+	winner, name := -1, "existing "+filepath.Base(target)
+	var best os.FileInfo
+	rest := g
+	if existing {
+		best = keys.info(target)
+	} else {
+		winner, name, best = g[0], items[g[0]].OldName, keys.info(items[g[0]].OldPath)
+		rest = g[1:]
+	}
+	for _, i := range rest {
+		if fi := keys.info(items[i].OldPath); better(fi, best) {
+			winner, name, best = i, items[i].OldName, fi
+		}
+	}
+
+	adj := "newer"
+	if policy == CollisionKeepLarger {
+		adj = "larger"
+	}
+	return winner, fmt.Sprintf("kept %s %s", adj, name)
+}
+
+// assignBackups picks a free "name.bak", "name.bak2", … next to the target
+// of every item flagged in backup.
+func assignBackups(items []RenamePlanItem, backup []bool, policy string, keys *pathKeys) {
+	vacated := vacatedPaths(items, keys)
+	claimed := map[string]bool{}
+	for _, it := range items {
+		if it.Status == StatusOK {
+			claimed[keys.of(it.NewPath)] = true
+		}
+	}
+
+	for i := range items {
+		it := &items[i]
+		if !backup[i] || it.Status != StatusOK {
+			continue
+		}
+		for n := 1; n < maxCollisionAttempts; n++ {
+			path := it.NewPath + ".bak"
+			if n > 1 {
+				path += fmt.Sprint(n)
+			}
+			k := keys.of(path)
+			if claimed[k] {
+				continue
+			}
+			if keys.exists("", path) && !vacated[k] {
+				continue
+			}
+			claimed[k] = true
+			it.BackupPath = path
+			break
+		}
+		if it.BackupPath == "" {
+			it.Status = StatusSkip
+			it.Reason = ReasonTargetExists
+			continue
+		}
+
+		what := "overwrite"
+		switch policy {
+		case CollisionKeepNewer:
+			what = "kept newer"
+		case CollisionKeepLarger:
+			what = "kept larger"
+		}
+		it.Reason = fmt.Sprintf("%s%s, existing file backed up as %s", ReasonCollision, what, filepath.Base(it.BackupPath))
+	}
+}
+
+// vacatedPaths is the set of sources that move away when the plan runs.
+func vacatedPaths(items []RenamePlanItem, keys *pathKeys) map[string]bool {
+	vacated := map[string]bool{}
+	for _, it := range items {
+		if it.Status == StatusOK {
+			vacated[keys.of(it.OldPath)] = true
+		}
+	}
+	return vacated
+}
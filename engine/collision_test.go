@@ -0,0 +1,94 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPlanCollisionPolicies(t *testing.T) {
+	type want struct {
+		status, newName, backup string
+	}
+	skip := func(reason string) want { return want{StatusSkip, reason, ""} }
+
+	// a.txt and c.txt both become b.txt, which is on disk already. By age
+	// a < b < c, by size a < c < b.
+	tests := []struct {
+		name     string
+		policy   string
+		selected []string
+		extra    []string // more files on disk
+		want     map[string]want
+	}{
+		{"skip", CollisionSkip, []string{"a.txt", "c.txt"}, nil, map[string]want{
+			"a.txt": skip(ReasonDuplicate), "c.txt": skip(ReasonDuplicate),
+		}},
+		{"skip existing", CollisionSkip, []string{"a.txt"}, nil, map[string]want{
+			"a.txt": skip(ReasonTargetExists),
+		}},
+		{"counter", CollisionCounter, []string{"a.txt", "c.txt"}, []string{"b (2).txt"}, map[string]want{
+			"a.txt": {StatusOK, "b (3).txt", ""}, "c.txt": {StatusOK, "b (4).txt", ""},
+		}},
+		{"underscore", CollisionUnderscore, []string{"a.txt", "c.txt"}, nil, map[string]want{
+			"a.txt": {StatusOK, "b_2.txt", ""}, "c.txt": {StatusOK, "b_3.txt", ""},
+		}},
+		{"overwrite", CollisionOverwrite, []string{"a.txt"}, nil, map[string]want{
+			"a.txt": {StatusOK, "b.txt", "b.txt.bak"},
+		}},
+		{"overwrite taken backup", CollisionOverwrite, []string{"a.txt"}, []string{"b.txt.bak"}, map[string]want{
+			"a.txt": {StatusOK, "b.txt", "b.txt.bak2"},
+		}},
+		{"overwrite duplicates", CollisionOverwrite, []string{"a.txt", "c.txt"}, nil, map[string]want{
+			"a.txt": skip(ReasonDuplicate), "c.txt": skip(ReasonDuplicate),
+		}},
+		{"keep newer", CollisionKeepNewer, []string{"a.txt", "c.txt"}, nil, map[string]want{
+			"a.txt": skip(ReasonCollision + "kept newer c.txt"), "c.txt": {StatusOK, "b.txt", "b.txt.bak"},
+		}},
+		{"keep larger", CollisionKeepLarger, []string{"a.txt", "c.txt"}, nil, map[string]want{
+			"a.txt": skip(ReasonCollision + "kept larger existing b.txt"),
+			"c.txt": skip(ReasonCollision + "kept larger existing b.txt"),
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, map[string]string{"a.txt": "A", "b.txt": "BBBB", "c.txt": "CC"})
+			for _, name := range tc.extra {
+				writeFiles(t, dir, map[string]string{name: ""})
+			}
+			now := time.Now()
+			for i, name := range []string{"a.txt", "b.txt", "c.txt"} {
+				mod := now.Add(time.Duration(i-3) * time.Hour)
+				if err := os.Chtimes(filepath.Join(dir, name), mod, mod); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var selected []string
+			for _, name := range tc.selected {
+				selected = append(selected, filepath.Join(dir, name))
+			}
+			opts := Options{
+				Steps:     []RenameStep{{Op: OpRegexReplace, A: "^[ac]", B: "b"}},
+				Collision: tc.policy,
+				CaseFS:    CaseFSSensitive,
+			}
+			items, _ := Plan(selected, opts)
+			for _, it := range items {
+				w := tc.want[it.OldName]
+				got := want{it.Status, it.NewName, filepath.Base(it.BackupPath)}
+				if it.Status == StatusSkip {
+					got = skip(it.Reason)
+				}
+				if it.BackupPath == "" {
+					got.backup = ""
+				}
+				if got != w {
+					t.Errorf("%s: got %+v, want %+v", it.OldName, got, w)
+				}
+			}
+		})
+	}
+}
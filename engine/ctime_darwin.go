@@ -0,0 +1,18 @@
+package engine
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// createdTime returns the birth time recorded by APFS/HFS+.
+func createdTime(_ string, fi os.FileInfo) time.Time {
+	if fi == nil {
+		return time.Time{}
+	}
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(st.Birthtimespec.Unix())
+	}
+	return fi.ModTime()
+}
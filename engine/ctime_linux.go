@@ -0,0 +1,20 @@
+package engine
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// createdTime returns the birth time from statx when the filesystem records
+// it, otherwise the modification time. Like os.Stat, which {size} and {date}
+// read, it follows symlinks.
+func createdTime(path string, fi os.FileInfo) time.Time {
+	var st unix.Statx_t
+	err := unix.Statx(unix.AT_FDCWD, path, 0, unix.STATX_BTIME, &st)
+	if err == nil && st.Mask&unix.STATX_BTIME != 0 {
+		return time.Unix(st.Btime.Sec, int64(st.Btime.Nsec))
+	}
+	return modTime(fi)
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !windows
+
+package engine
+
+import (
+	"os"
+	"time"
+)
+
+// createdTime falls back to the modification time on platforms without a
+// portable birth time.
+func createdTime(_ string, fi os.FileInfo) time.Time {
+	return modTime(fi)
+}
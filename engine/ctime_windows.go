@@ -0,0 +1,18 @@
+package engine
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// createdTime returns the NTFS/FAT creation time.
+func createdTime(_ string, fi os.FileInfo) time.Time {
+	if fi == nil {
+		return time.Time{}
+	}
+	if d, ok := fi.Sys().(*syscall.Win32FileAttributeData); ok {
+		return time.Unix(0, d.CreationTime.Nanoseconds())
+	}
+	return fi.ModTime()
+}
@@ -0,0 +1,129 @@
+package engine
+
+/* -------------------- Name diff -------------------- */
+
+// DiffOp says which name a DiffSpan's text is from.
+type DiffOp int
+
+const (
+	DiffEqual  DiffOp = iota // in both names
+	DiffDelete               // only in the old name
+	DiffInsert               // only in the new name
+)
+
+type DiffSpan struct {
+	Op   DiffOp
+	Text string
+}
+
+// diffMinEqual is the shortest run of shared characters kept between two
+// changes; shorter ones (the "_" two unrelated names happen to share) are
+// folded into the change around them, which reads better.
+const diffMinEqual = 3
+
+// DiffNames splits the change from old to new into runs of kept, removed
+// and inserted characters, e.g. "IMG_0042.JPG" → "Trip_0042.jpg" is
+// -"IMG" +"Trip" "_0042." -"JPG" +"jpg". Within a change the removed text
+// comes first.
+func DiffNames(old, new string) []DiffSpan {
+	a, b := []rune(old), []rune(new)
+
+	// trim the common prefix and suffix, then align the middle by longest
+	// common subsequence (names are short, so the quadratic table is fine)
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+	ma, mb := a[pre:len(a)-suf], b[pre:len(b)-suf]
+
+	lcs := make([][]int, len(ma)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(mb)+1)
+	}
+	for i := len(ma) - 1; i >= 0; i-- {
+		for j := len(mb) - 1; j >= 0; j-- {
+			if ma[i] == mb[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var d differ
+	d.add(DiffEqual, a[:pre])
+	i, j := 0, 0
+	for i < len(ma) || j < len(mb) {
+		switch {
+		case i < len(ma) && j < len(mb) && ma[i] == mb[j]:
+			d.add(DiffEqual, ma[i:i+1])
+			i, j = i+1, j+1
+		case j < len(mb) && (i == len(ma) || lcs[i][j+1] >= lcs[i+1][j]):
+			d.add(DiffInsert, mb[j:j+1])
+			j++
+		default:
+			d.add(DiffDelete, ma[i:i+1])
+			i++
+		}
+	}
+	d.add(DiffEqual, a[len(a)-suf:])
+	return d.spans()
+}
+
+// differ collects a diff one piece at a time. Pieces of a change pile up in
+// del and ins until a long enough equal run ends it.
+type differ struct {
+	out      []DiffSpan
+	del, ins []rune
+	eq       []rune // equal run since the last change, not yet kept
+}
+
+func (d *differ) add(op DiffOp, r []rune) {
+	switch op {
+	case DiffDelete:
+		d.fold()
+		d.del = append(d.del, r...)
+	case DiffInsert:
+		d.fold()
+		d.ins = append(d.ins, r...)
+	default:
+		d.eq = append(d.eq, r...)
+	}
+}
+
+// fold makes a short equal run between two changes part of the change.
+func (d *differ) fold() {
+	if len(d.eq) == 0 {
+		return
+	}
+	if len(d.eq) < diffMinEqual && len(d.del)+len(d.ins) > 0 {
+		d.del = append(d.del, d.eq...)
+		d.ins = append(d.ins, d.eq...)
+	} else {
+		d.flush()
+		d.out = append(d.out, DiffSpan{DiffEqual, string(d.eq)})
+	}
+	d.eq = nil
+}
+
+func (d *differ) flush() {
+	if len(d.del) > 0 {
+		d.out = append(d.out, DiffSpan{DiffDelete, string(d.del)})
+	}
+	if len(d.ins) > 0 {
+		d.out = append(d.out, DiffSpan{DiffInsert, string(d.ins)})
+	}
+	d.del, d.ins = nil, nil
+}
+
+func (d *differ) spans() []DiffSpan {
+	d.flush()
+	if len(d.eq) > 0 {
+		d.out = append(d.out, DiffSpan{DiffEqual, string(d.eq)})
+	}
+	return d.out
+}
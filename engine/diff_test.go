@@ -0,0 +1,97 @@
+package engine
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// spanString writes spans as "=kept", "-removed" and "+inserted".
+func spanString(spans []DiffSpan) []string {
+	var out []string
+	for _, s := range spans {
+		out = append(out, string("=-+"[s.Op])+s.Text)
+	}
+	return out
+}
+
+func TestDiffNames(t *testing.T) {
+	tests := []struct {
+		old, new string
+		want     []string
+	}{
+		{"", "", nil},
+		{"a.txt", "a.txt", []string{"=a.txt"}},
+		{"", "abc", []string{"+abc"}},
+		{"abc", "", []string{"-abc"}},
+		{"a.txt", "b.txt", []string{"-a", "+b", "=.txt"}},
+		{"IMG_0042.JPG", "Trip_0042.jpg", []string{"-IMG", "+Trip", "=_0042.", "-JPG", "+jpg"}},
+		{"photo_ab_2024.jpg", "photo_ba_2024.jpg", []string{"=photo_", "-ab", "+ba", "=_2024.jpg"}},
+		{"report.pdf", "report (1).pdf", []string{"=report", "+ (1)", "=.pdf"}},
+		{"draft final.doc", "final.doc", []string{"-draft ", "=final.doc"}},
+
+		// an equal run shorter than diffMinEqual between changes is folded
+		// into them
+		{"a_b", "x_y", []string{"-a_b", "+x_y"}},
+		{"abc-123-def", "xyz-123-uvw", []string{"-abc", "+xyz", "=-123-", "-def", "+uvw"}},
+
+		// runes, not bytes
+		{"café.txt", "cafè.txt", []string{"=caf", "-é", "+è", "=.txt"}},
+		{"Жук.txt", "Жуки.txt", []string{"=Жук", "+и", "=.txt"}},
+		{"🎉 a", "🎉 b", []string{"=🎉 ", "-a", "+b"}},
+		{"日本語", "日本", []string{"=日本", "-語"}},
+	}
+	for _, tc := range tests {
+		got := DiffNames(tc.old, tc.new)
+		if s := spanString(got); !slices.Equal(s, tc.want) {
+			t.Errorf("DiffNames(%q, %q) = %q, want %q", tc.old, tc.new, s, tc.want)
+		}
+
+		// whatever the alignment, the spans must spell out both names
+		var a, b strings.Builder
+		for _, s := range got {
+			if s.Text == "" {
+				t.Errorf("DiffNames(%q, %q): empty span", tc.old, tc.new)
+			}
+			if s.Op != DiffInsert {
+				a.WriteString(s.Text)
+			}
+			if s.Op != DiffDelete {
+				b.WriteString(s.Text)
+			}
+		}
+		if a.String() != tc.old || b.String() != tc.new {
+			t.Errorf("DiffNames(%q, %q) spells %q → %q", tc.old, tc.new, a.String(), b.String())
+		}
+	}
+}
+
+func TestDifferFold(t *testing.T) {
+	tests := []struct {
+		name string
+		ops  []DiffSpan
+		want []string
+	}{
+		{"short run between changes", []DiffSpan{{DiffDelete, "a"}, {DiffEqual, "_"}, {DiffInsert, "b"}},
+			[]string{"-a_", "+_b"}},
+		{"long run between changes", []DiffSpan{{DiffDelete, "a"}, {DiffEqual, "___"}, {DiffInsert, "b"}},
+			[]string{"-a", "=___", "+b"}},
+		{"short run before any change", []DiffSpan{{DiffEqual, "x"}, {DiffInsert, "b"}},
+			[]string{"=x", "+b"}},
+		{"short run at the end", []DiffSpan{{DiffDelete, "a"}, {DiffEqual, "x"}},
+			[]string{"-a", "=x"}},
+		{"removed text first", []DiffSpan{{DiffInsert, "b"}, {DiffDelete, "a"}},
+			[]string{"-a", "+b"}},
+		{"equal pieces join", []DiffSpan{{DiffEqual, "a"}, {DiffEqual, "b"}, {DiffEqual, "c"}},
+			[]string{"=abc"}},
+	}
+	for _, tc := range tests {
+		var d differ
+		for _, op := range tc.ops {
+			d.add(op.Op, []rune(op.Text))
+		}
+		if got := spanString(d.spans()); !slices.Equal(got, tc.want) {
+			t.Errorf("%s: %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
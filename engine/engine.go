@@ -0,0 +1,138 @@
+// Package engine is the GUI-free core of RenForge: it scans a folder,
+// filters the files, builds a validated rename plan and applies it.
+//
+// A typical run goes scan → filter → plan → apply:
+//
+//	opts := engine.Options{Folder: dir, Steps: steps}
+//	files, err := engine.Scan(opts)
+//	matched := engine.Filter(files, opts)
+//	plan, summary := engine.Plan(matched, opts)
+//	results := engine.Apply(plan)
+package engine
+
+/* -------------------- Filters -------------------- */
+
+type FilterRule struct {
+	ID    int    `json:"-"`
+	Mode  string `json:"mode"` // one of FilterModes
+	Value string `json:"value"`
+	Not   bool   `json:"not,omitempty"` // keep the files the rule doesn't match
+}
+
+// FilterGroup combines its rules and subgroups with AND (MatchAll) or OR,
+// so "(ext jpg OR ext png) AND NOT contains thumb" is an AND group holding
+// a negated rule and an OR group. Rules and groups share one ID space.
+type FilterGroup struct {
+	ID       int           `json:"-"`
+	MatchAll bool          `json:"match_all"`
+	Not      bool          `json:"not,omitempty"`
+	Rules    []FilterRule  `json:"rules,omitempty"`
+	Groups   []FilterGroup `json:"groups,omitempty"`
+}
+
+// FilterModes lists the supported FilterRule modes in UI order: the first
+// five match the base name, glob and path the path relative to the scanned
+// folder, the rest the file's metadata (see metafilter.go).
+var FilterModes = []string{
+	"contains", "starts with", "ends with", "extension", "regex",
+	"glob", "path",
+	"size", "modified", "created", "hidden", "read-only", "symlink",
+}
+
+/* -------------------- Rename Steps -------------------- */
+
+type RenameOp string
+
+const (
+	OpRemoveText      RenameOp = "Remove text"
+	OpReplaceText     RenameOp = "Replace text"
+	OpInsertBeforeExt RenameOp = "Insert before extension"
+	OpChangeExt       RenameOp = "Change extension"
+	OpAppend          RenameOp = "Append"
+	OpPrepend         RenameOp = "Prepend"
+	OpRegexReplace    RenameOp = "Regex replace"
+	OpNumber          RenameOp = "Number sequentially"
+	OpTemplate        RenameOp = "Template"
+	OpChangeCase      RenameOp = "Change case"
+	OpNormalize       RenameOp = "Normalize Unicode"
+	OpSanitize        RenameOp = "Sanitize"
+)
+
+// RenameOps lists the supported operations in UI order.
+var RenameOps = []RenameOp{
+	OpRemoveText,
+	OpReplaceText,
+	OpInsertBeforeExt,
+	OpChangeExt,
+	OpAppend,
+	OpPrepend,
+	OpRegexReplace,
+	OpNumber,
+	OpTemplate,
+	OpChangeCase,
+	OpNormalize,
+	OpSanitize,
+}
+
+type RenameStep struct {
+	ID int      `json:"-"`
+	Op RenameOp `json:"op"`
+	A  string   `json:"a,omitempty"`
+	B  string   `json:"b,omitempty"`
+
+	Num        NumberingOptions `json:"numbering,omitzero"`    // OpNumber only; A is the separator
+	Case       CaseOptions      `json:"case,omitzero"`         // OpChangeCase only
+	Sanitize   SanitizeOptions  `json:"sanitize,omitzero"`     // OpSanitize only
+	IgnoreCase bool             `json:"ignore_case,omitempty"` // OpRegexReplace only
+}
+
+/* -------------------- Options -------------------- */
+
+// Options carries everything needed to go from a folder to a rename plan.
+type Options struct {
+	Folder    string
+	Recursive bool
+	Scan      ScanOptions // depth, excludes, hidden files, ignore files, symlinks
+
+	Filters        FilterGroup
+	CaseSensitive  bool
+	NormalizeMatch bool // compare names and filter values in NFKC
+
+	Steps []RenameStep
+
+	Collision string // one of CollisionPolicies; "" skips colliding items
+	Profile   string // naming rules to check new names against (Profiles); "" is the host OS
+	CaseFS    string // whether names differing only in case collide (CaseFSModes); "" detects it
+
+	// Listing, when not nil, is a scan of the folder that planning checks
+	// sources and targets against instead of the disk: cheap enough to
+	// replan on every keystroke, but blind to anything the scan left out
+	// (folders, excluded or hidden files) or that changed since. Leave it
+	// nil for a plan that is going to be applied.
+	Listing []File
+}
+
+/* -------------------- Plan items -------------------- */
+
+const (
+	StatusOK      = "ok"
+	StatusSkip    = "skip"
+	StatusRenamed = "renamed"
+	StatusError   = "error"
+	StatusDryRun  = "dry-run"
+)
+
+type RenamePlanItem struct {
+	OldPath string
+	NewPath string
+	OldName string
+	NewName string
+	Status  string // "ok" | "skip" | "renamed" | "error" | "dry-run"
+	Reason  string
+
+	// BackupPath is where the file already at NewPath is moved first, when
+	// the collision policy overwrites it.
+	BackupPath string
+	// Warning notes a problem that doesn't stop the rename.
+	Warning string
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package engine
+
+import "os"
+
+// fileIDOf has nothing to go on outside Unix: os.SameFile compares Windows
+// file IDs without exposing them.
+func fileIDOf(os.FileInfo) (fileID, bool) {
+	return fileID{}, false
+}
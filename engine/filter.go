@@ -0,0 +1,325 @@
+package engine
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+/* -------------------- Filter engine -------------------- */
+
+// Filter returns the paths of the files matching opts.Filters. With
+// opts.NormalizeMatch, names and values are compared in NFKC so "é" typed
+// on one system matches "é" decomposed by another.
+func Filter(all []File, opts Options) []string {
+	if !opts.NormalizeMatch {
+		return FilterFilesMulti(all, opts.Folder, opts.Filters, opts.CaseSensitive)
+	}
+
+	g, active := opts.Filters.mapRules(func(r FilterRule) FilterRule {
+		if !metaModes[r.Mode] {
+			r.Value = norm.NFKC.String(r.Value)
+		}
+		return r
+	}).prune()
+	now := time.Now()
+	out := make([]string, 0, len(all))
+	for _, f := range all {
+		base := norm.NFKC.String(filepath.Base(f.Path))
+		rel := norm.NFKC.String(RelPath(opts.Folder, f.Path))
+		if !active || matchRules(base, rel, f, g, opts.CaseSensitive, now) {
+			out = append(out, f.Path)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// FilterFilesMulti is Filter without Unicode normalisation; glob and path
+// rules see paths relative to folder.
+func FilterFilesMulti(all []File, folder string, g FilterGroup, caseSensitive bool) []string {
+	g, active := g.prune()
+	now := time.Now()
+	out := make([]string, 0, len(all))
+	for _, f := range all {
+		if !active || matchRules(filepath.Base(f.Path), RelPath(folder, f.Path), f, g, caseSensitive, now) {
+			out = append(out, f.Path)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// MatchesRules reports whether f, listed under folder, passes the filter
+// tree g: name modes look at its base name, glob and path at its path
+// relative to folder, the others at the info captured when it was listed.
+func MatchesRules(f File, folder string, g FilterGroup, caseSensitive bool) bool {
+	g, active := g.prune()
+	return !active || matchRules(filepath.Base(f.Path), RelPath(folder, f.Path), f, g, caseSensitive, time.Now())
+}
+
+// RelPath is path relative to folder with forward slashes ("raw/IMG_1.CR2"),
+// the form glob and path filters match against. Paths outside folder are
+// returned as they are.
+func RelPath(folder, p string) string {
+	if folder != "" {
+		if rel, err := filepath.Rel(folder, p); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return filepath.ToSlash(p)
+}
+
+// matchRules evaluates a pruned tree against f, with the name and relative
+// path to match given separately (e.g. normalised) and relative dates
+// counted back from now.
+func matchRules(filename, rel string, f File, g FilterGroup, caseSensitive bool, now time.Time) bool {
+	name := filename
+	if !caseSensitive {
+		name = strings.ToLower(name)
+	}
+
+	ruleMatch := func(r FilterRule) bool {
+		val := strings.TrimSpace(r.Value)
+		if metaModes[r.Mode] {
+			return matchMeta(f, r.Mode, val, now)
+		}
+		if val == "" {
+			return true
+		}
+		check := name
+		v := val
+		if !caseSensitive {
+			v = strings.ToLower(val)
+		}
+
+		switch r.Mode {
+		case "contains":
+			return strings.Contains(check, v)
+		case "starts with":
+			return strings.HasPrefix(check, v)
+		case "ends with":
+			return strings.HasSuffix(check, v)
+		case "regex":
+			re, err := compileRegex(val, caseSensitive)
+			if err != nil {
+				// invalid patterns are reported by ValidateFilter and
+				// ignored here, the same as an empty value
+				return true
+			}
+			return re.MatchString(filename)
+		case "extension":
+			ext := filepath.Ext(filename)
+			vx := v
+			if !strings.HasPrefix(vx, ".") {
+				vx = "." + vx
+			}
+			if !caseSensitive {
+				ext = strings.ToLower(ext)
+				vx = strings.ToLower(vx)
+			}
+			return ext == vx
+		case "glob":
+			return matchGlob(val, rel, caseSensitive)
+		case "path":
+			p := rel
+			if !caseSensitive {
+				p = strings.ToLower(p)
+			}
+			return strings.Contains(p, strings.ReplaceAll(v, "\\", "/"))
+		default:
+			return strings.Contains(check, v)
+		}
+	}
+
+	return g.eval(ruleMatch)
+}
+
+// eval combines the rules and subgroups of g, stopping at the first one that
+// decides the group: a miss under AND, a match under OR.
+func (g FilterGroup) eval(ruleMatch func(FilterRule) bool) bool {
+	decided := false
+	for _, r := range g.Rules {
+		if (ruleMatch(r) != r.Not) != g.MatchAll {
+			decided = true
+			break
+		}
+	}
+	for i := 0; i < len(g.Groups) && !decided; i++ {
+		decided = g.Groups[i].eval(ruleMatch) != g.MatchAll
+	}
+	return (g.MatchAll != decided) != g.Not
+}
+
+// prune drops the rules that don't filter anything yet (empty or invalid
+// values) and the groups left empty, so they neither keep nor exclude files.
+// active is false when nothing is left.
+func (g FilterGroup) prune() (out FilterGroup, active bool) {
+	out = FilterGroup{ID: g.ID, MatchAll: g.MatchAll, Not: g.Not}
+	for _, r := range g.Rules {
+		if ValidateFilter(r) != nil || (strings.TrimSpace(r.Value) == "" && !flagModes[r.Mode]) {
+			continue
+		}
+		out.Rules = append(out.Rules, r)
+	}
+	for _, sub := range g.Groups {
+		if sub, ok := sub.prune(); ok {
+			out.Groups = append(out.Groups, sub)
+		}
+	}
+	return out, len(out.Rules)+len(out.Groups) > 0
+}
+
+func (g FilterGroup) mapRules(fn func(FilterRule) FilterRule) FilterGroup {
+	out := FilterGroup{ID: g.ID, MatchAll: g.MatchAll, Not: g.Not}
+	for _, r := range g.Rules {
+		out.Rules = append(out.Rules, fn(r))
+	}
+	for _, sub := range g.Groups {
+		out.Groups = append(out.Groups, sub.mapRules(fn))
+	}
+	return out
+}
+
+/* -------------------- Glob -------------------- */
+
+// matchGlob matches a relative path against a glob: "*", "?" and "[a-z]"
+// stay within one folder, "**" spans any number of folders. A pattern
+// without a "/" matches the file name in any folder, so "*.CR2" works like
+// "**/*.CR2". Folders are separated by "/" on every system.
+func matchGlob(pattern, rel string, caseSensitive bool) bool {
+	pattern = strings.Trim(pattern, "/")
+	if !caseSensitive {
+		pattern, rel = strings.ToLower(pattern), strings.ToLower(rel)
+	}
+	if !strings.Contains(pattern, "/") {
+		pattern = "**/" + pattern
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(rel, "/"))
+}
+
+func matchSegments(pat, segs []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			for i := 0; i <= len(segs); i++ {
+				if matchSegments(pat[1:], segs[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segs) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pat[0], segs[0]); !ok {
+			return false
+		}
+		pat, segs = pat[1:], segs[1:]
+	}
+	return len(segs) == 0
+}
+
+func validateGlob(pattern string) error {
+	for _, seg := range strings.Split(pattern, "/") {
+		if _, err := path.Match(seg, ""); err != nil {
+			return fmt.Errorf("invalid glob %q", pattern)
+		}
+	}
+	return nil
+}
+
+/* -------------------- Filter tree editing -------------------- */
+
+// Clone returns a deep copy of g.
+func (g FilterGroup) Clone() FilterGroup {
+	return g.mapRules(func(r FilterRule) FilterRule { return r })
+}
+
+// Empty reports whether g has no rules or groups at all.
+func (g FilterGroup) Empty() bool {
+	return len(g.Rules) == 0 && len(g.Groups) == 0
+}
+
+// Rule finds the rule with the given ID anywhere in g.
+func (g *FilterGroup) Rule(id int) *FilterRule {
+	for i := range g.Rules {
+		if g.Rules[i].ID == id {
+			return &g.Rules[i]
+		}
+	}
+	for i := range g.Groups {
+		if r := g.Groups[i].Rule(id); r != nil {
+			return r
+		}
+	}
+	return nil
+}
+
+// Group finds the group with the given ID: g itself or one nested in it.
+func (g *FilterGroup) Group(id int) *FilterGroup {
+	if g.ID == id {
+		return g
+	}
+	for i := range g.Groups {
+		if sub := g.Groups[i].Group(id); sub != nil {
+			return sub
+		}
+	}
+	return nil
+}
+
+// Remove deletes the rule or group with the given ID from g.
+func (g *FilterGroup) Remove(id int) bool {
+	for i := range g.Rules {
+		if g.Rules[i].ID == id {
+			g.Rules = append(g.Rules[:i:i], g.Rules[i+1:]...)
+			return true
+		}
+	}
+	for i := range g.Groups {
+		if g.Groups[i].ID == id {
+			g.Groups = append(g.Groups[:i:i], g.Groups[i+1:]...)
+			return true
+		}
+		if g.Groups[i].Remove(id) {
+			return true
+		}
+	}
+	return false
+}
+
+// Renumber gives g and everything in it IDs from next on, depth first, and
+// returns the next free ID.
+func (g *FilterGroup) Renumber(next int) int {
+	g.ID = next
+	next++
+	for i := range g.Rules {
+		g.Rules[i].ID = next
+		next++
+	}
+	for i := range g.Groups {
+		next = g.Groups[i].Renumber(next)
+	}
+	return next
+}
+
+// ValidateFilter reports a problem with a rule's value, e.g. a regex that
+// does not compile. A nil error means the rule is usable.
+func ValidateFilter(r FilterRule) error {
+	if metaModes[r.Mode] {
+		return validateMeta(r.Mode, strings.TrimSpace(r.Value))
+	}
+	if r.Mode == "glob" {
+		return validateGlob(strings.TrimSpace(r.Value))
+	}
+	if r.Mode != "regex" || strings.TrimSpace(r.Value) == "" {
+		return nil
+	}
+	_, err := compileRegex(strings.TrimSpace(r.Value), true)
+	return err
+}
@@ -0,0 +1,61 @@
+package engine
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestFilterGroups(t *testing.T) {
+	folder := filepath.FromSlash("/photos")
+	var all []File
+	for name, size := range map[string]int64{
+		"a.jpg": 100, "a_thumb.jpg": 10, "b.PNG": 2000, "notes.txt": 5,
+		"raw/c.CR2": 30000, "raw/2024/d.cr2": 40000,
+	} {
+		p := filepath.Join(folder, filepath.FromSlash(name))
+		all = append(all, File{Path: p, Info: fakeInfo{name: filepath.Base(p), size: size}})
+	}
+	rule := func(mode, value string) FilterRule { return FilterRule{Mode: mode, Value: value} }
+	not := func(r FilterRule) FilterRule { r.Not = true; return r }
+	or := func(rules ...FilterRule) FilterGroup { return FilterGroup{Rules: rules} }
+
+	tests := []struct {
+		name string
+		g    FilterGroup
+		want []string
+	}{
+		{"no rules", FilterGroup{MatchAll: true}, []string{
+			"a.jpg", "a_thumb.jpg", "b.PNG", "notes.txt", "raw/2024/d.cr2", "raw/c.CR2"}},
+		{"(jpg or png) and not thumb", FilterGroup{
+			MatchAll: true,
+			Rules:    []FilterRule{not(rule("contains", "thumb"))},
+			Groups:   []FilterGroup{or(rule("extension", "jpg"), rule("extension", ".png"))},
+		}, []string{"a.jpg", "b.PNG"}},
+		{"negated group", FilterGroup{
+			MatchAll: true,
+			Groups:   []FilterGroup{{Not: true, Rules: []FilterRule{rule("extension", "jpg"), rule("glob", "raw/**")}}},
+		}, []string{"b.PNG", "notes.txt"}},
+		{"glob in any folder", FilterGroup{MatchAll: true, Rules: []FilterRule{rule("glob", "*.cr2")}},
+			[]string{"raw/2024/d.cr2", "raw/c.CR2"}},
+		{"glob one level", FilterGroup{MatchAll: true, Rules: []FilterRule{rule("glob", "raw/*.cr2")}},
+			[]string{"raw/c.CR2"}},
+		{"size and path", FilterGroup{MatchAll: true, Rules: []FilterRule{rule("size", ">35KB"), rule("path", "raw/")}},
+			[]string{"raw/2024/d.cr2"}},
+		{"regex, empty and broken rules", FilterGroup{MatchAll: true, Rules: []FilterRule{
+			rule("regex", `^a(_|\.)`), rule("contains", " "), rule("regex", "(")}},
+			[]string{"a.jpg", "a_thumb.jpg"}},
+		{"or with bad size", or(rule("size", "huge"), rule("starts with", "NOTES")),
+			[]string{"notes.txt"}},
+	}
+	for _, tc := range tests {
+		opts := Options{Folder: folder, Filters: tc.g}
+		var got []string
+		for _, p := range Filter(all, opts) {
+			got = append(got, RelPath(folder, p))
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
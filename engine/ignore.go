@@ -0,0 +1,103 @@
+package engine
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+/* -------------------- .gitignore -------------------- */
+
+// ignoreFiles are read in every scanned folder when ScanOptions.UseIgnore is
+// set; .ignore (used by ripgrep and friends) comes last so it can override.
+var ignoreFiles = []string{".gitignore", ".ignore"}
+
+// ignoreRule is one line of an ignore file, in the usual gitignore syntax:
+// "#" comments, "!" re-includes, a trailing "/" only matches folders, and a
+// pattern with a "/" elsewhere is anchored to the file's folder.
+type ignoreRule struct {
+	base     string // folder of the ignore file, relative to the scan root ("" for the root)
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// readIgnoreFiles parses the ignore files in dir, whose path relative to
+// the scan root is rel. Missing or unreadable files add no rules.
+func readIgnoreFiles(dir, rel string) []ignoreRule {
+	if rel == "." {
+		rel = ""
+	}
+	var rules []ignoreRule
+	for _, name := range ignoreFiles {
+		f, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		sc := bufio.NewScanner(f)
+		for sc.Scan() {
+			if r, ok := parseIgnoreLine(sc.Text(), rel); ok {
+				rules = append(rules, r)
+			}
+		}
+		f.Close()
+	}
+	return rules
+}
+
+func parseIgnoreLine(line, base string) (ignoreRule, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+	r := ignoreRule{base: base}
+	if rest, ok := strings.CutPrefix(line, "!"); ok {
+		r.negate, line = true, rest
+	} else if strings.HasPrefix(line, `\#`) || strings.HasPrefix(line, `\!`) {
+		line = line[1:]
+	}
+	if rest, ok := strings.CutSuffix(line, "/"); ok {
+		r.dirOnly, line = true, rest
+	}
+	if strings.Contains(line, "/") {
+		r.anchored, line = true, strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return ignoreRule{}, false
+	}
+	r.pattern = line
+	return r, true
+}
+
+func (r ignoreRule) match(rel string) bool {
+	if r.base != "" {
+		sub, ok := strings.CutPrefix(rel, r.base+"/")
+		if !ok {
+			return false
+		}
+		rel = sub
+	}
+	if r.anchored {
+		return matchSegments(strings.Split(r.pattern, "/"), strings.Split(rel, "/"))
+	}
+	ok, _ := path.Match(r.pattern, path.Base(rel))
+	return ok
+}
+
+// ignored applies rules in order, the last match winning, to the file or
+// folder at rel (relative to the scan root).
+func ignored(rules []ignoreRule, rel string, isDir bool) bool {
+	out := false
+	for _, r := range rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		if r.match(rel) {
+			out = !r.negate
+		}
+	}
+	return out
+}
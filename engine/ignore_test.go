@@ -0,0 +1,70 @@
+package engine
+
+import "testing"
+
+func TestParseIgnoreLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want ignoreRule
+		ok   bool
+	}{
+		{"", ignoreRule{}, false},
+		{"# comment", ignoreRule{}, false},
+		{"/", ignoreRule{}, false},
+		{"*.log  ", ignoreRule{base: "b", pattern: "*.log"}, true},
+		{"!keep.log", ignoreRule{base: "b", pattern: "keep.log", negate: true}, true},
+		{`\#notes`, ignoreRule{base: "b", pattern: "#notes"}, true},
+		{`\!bang`, ignoreRule{base: "b", pattern: "!bang"}, true},
+		{"build/", ignoreRule{base: "b", pattern: "build", dirOnly: true}, true},
+		{"/todo.txt", ignoreRule{base: "b", pattern: "todo.txt", anchored: true}, true},
+		{"docs/*.md", ignoreRule{base: "b", pattern: "docs/*.md", anchored: true}, true},
+		{"!/out/", ignoreRule{base: "b", pattern: "out", negate: true, dirOnly: true, anchored: true}, true},
+	}
+	for _, tc := range tests {
+		got, ok := parseIgnoreLine(tc.line, "b")
+		if ok != tc.ok || (ok && got != tc.want) {
+			t.Errorf("parseIgnoreLine(%q) = %+v, %v; want %+v, %v", tc.line, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestIgnored(t *testing.T) {
+	var rules []ignoreRule
+	add := func(base string, lines ...string) {
+		for _, l := range lines {
+			if r, ok := parseIgnoreLine(l, base); ok {
+				rules = append(rules, r)
+			}
+		}
+	}
+	add("", "*.log", "!keep.log", "build/", "/todo.txt", "docs/*.md")
+	add("sub", "*.tmp", "!/keep.log")
+
+	tests := []struct {
+		rel   string
+		isDir bool
+		want  bool
+	}{
+		{"a.log", false, true},
+		{"deep/a.log", false, true},
+		{"keep.log", false, false},      // re-included
+		{"build", true, true},           // folder pattern
+		{"build", false, false},         // ... doesn't match a file
+		{"sub/build", true, true},       // unanchored: any depth
+		{"todo.txt", false, true},       // anchored to the root
+		{"sub/todo.txt", false, false},  // ... so not below it
+		{"docs/a.md", false, true},      // a slash inside anchors too
+		{"x/docs/a.md", false, false},   // ...
+		{"docs/sub/a.md", false, false}, // "*" stays within a folder
+		{"sub/a.tmp", false, true},      // sub's own rules
+		{"a.tmp", false, false},         // ... don't reach its parent
+		{"sub/deeper/a.tmp", false, true},
+		{"sub/keep.log", false, false},
+		{"sub/x/keep.log", false, false}, // the root's "!keep.log"
+	}
+	for _, tc := range tests {
+		if got := ignored(rules, tc.rel, tc.isDir); got != tc.want {
+			t.Errorf("ignored(%s, dir=%v) = %v, want %v", tc.rel, tc.isDir, got, tc.want)
+		}
+	}
+}
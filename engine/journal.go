@@ -0,0 +1,458 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+/* -------------------- Journal -------------------- */
+
+// Apply records what it is about to do in a journal file before each phase,
+// so a crash or power loss between phase 1 and phase 2 does not leave files
+// stranded under their .renforge_tmp_ names with no record of who they were.
+// FindRecovery finds the leftovers and Recover finishes or undoes the batch.
+
+const (
+	tmpPrefix     = ".renforge_tmp_"
+	journalPrefix = ".renforge_journal_"
+	journalExt    = ".json"
+
+	JournalVersion = 1
+
+	// JournalStaging: phase 1 (original → temp) was under way. Entries
+	// without a temp file were never moved.
+	JournalStaging = "staging"
+	// JournalFinalizing: phase 1 is done and only lists the files it moved;
+	// phase 2 (temp → final) was under way. Entries without a temp file
+	// already have their final name.
+	JournalFinalizing = "finalizing"
+)
+
+// JournalEntry is one file of a batch. Paths are absolute in memory and
+// relative to the journal's folder on disk.
+type JournalEntry struct {
+	Original string `json:"original"`
+	Tmp      string `json:"tmp"`
+	Final    string `json:"final"`
+}
+
+type Journal struct {
+	Path    string         `json:"-"`
+	Version int            `json:"version"`
+	Started time.Time      `json:"started"`
+	Phase   string         `json:"phase"`
+	Entries []JournalEntry `json:"entries"`
+}
+
+// IsInternalName reports whether name is one of RenForge's own temp or
+// journal files, which are hidden from listings.
+func IsInternalName(name string) bool {
+	return strings.HasPrefix(name, tmpPrefix) || strings.HasPrefix(name, journalPrefix)
+}
+
+// startJournal makes the paths of entries absolute and writes the phase-1
+// journal in the folder that contains all of them.
+func startJournal(entries []JournalEntry, ts int64) (*Journal, error) {
+	var dirs []string
+	for i, e := range entries {
+		var err error
+		for _, p := range []*string{&entries[i].Original, &entries[i].Tmp, &entries[i].Final} {
+			if *p, err = filepath.Abs(*p); err != nil {
+				return nil, err
+			}
+		}
+		dirs = append(dirs, filepath.Dir(e.Original))
+	}
+	dir, err := commonDir(dirs)
+	if err != nil {
+		return nil, err
+	}
+	j := &Journal{
+		Path:    filepath.Join(dir, fmt.Sprintf("%s%d%s", journalPrefix, ts, journalExt)),
+		Version: JournalVersion,
+		Started: time.Unix(0, ts),
+		Phase:   JournalStaging,
+		Entries: entries,
+	}
+	return j, j.save()
+}
+
+// save replaces the journal on disk atomically and fsyncs it, so the file is
+// either the previous phase or the new one, never half written.
+func (j *Journal) save() error {
+	dir := filepath.Dir(j.Path)
+	disk := *j
+	disk.Entries = make([]JournalEntry, len(j.Entries))
+	for i, e := range j.Entries {
+		disk.Entries[i] = JournalEntry{
+			Original: relTo(dir, e.Original),
+			Tmp:      relTo(dir, e.Tmp),
+			Final:    relTo(dir, e.Final),
+		}
+	}
+	data, err := json.MarshalIndent(disk, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	part := j.Path + ".part"
+	f, err := os.OpenFile(part, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("journal: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("journal: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("journal: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("journal: %w", err)
+	}
+	if err := os.Rename(part, j.Path); err != nil {
+		return fmt.Errorf("journal: %w", err)
+	}
+	syncDir(dir)
+	return nil
+}
+
+func (j *Journal) remove() {
+	_ = os.Remove(j.Path)
+	syncDir(filepath.Dir(j.Path))
+}
+
+// LoadJournal reads a journal written by Apply.
+func LoadJournal(path string) (*Journal, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var j Journal
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	if j.Version < 1 || j.Version > JournalVersion {
+		return nil, fmt.Errorf("%s: unsupported journal version %d", filepath.Base(path), j.Version)
+	}
+	if j.Phase != JournalStaging && j.Phase != JournalFinalizing {
+		return nil, fmt.Errorf("%s: unknown phase %q", filepath.Base(path), j.Phase)
+	}
+	dir := filepath.Dir(path)
+	j.Path = path
+	for i, e := range j.Entries {
+		j.Entries[i] = JournalEntry{
+			Original: filepath.Join(dir, e.Original),
+			Tmp:      filepath.Join(dir, e.Tmp),
+			Final:    filepath.Join(dir, e.Final),
+		}
+	}
+	return &j, nil
+}
+
+// Stranded lists the entries whose file is still under its temp name.
+func (j *Journal) Stranded() []JournalEntry {
+	var out []JournalEntry
+	for _, e := range j.Entries {
+		if exists(e.Tmp) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+/* -------------------- Recovery -------------------- */
+
+// Recovery is what an interrupted Apply left behind under a folder.
+type Recovery struct {
+	Journals []*Journal
+	Orphans  []string // temp files no journal accounts for
+	Errors   []string // journals and folders that could not be read
+}
+
+func (r Recovery) Empty() bool {
+	return len(r.Journals) == 0 && len(r.Orphans) == 0 && len(r.Errors) == 0
+}
+
+// FindRecovery looks for leftover temp files and journals in folder (and its
+// subfolders when recursive is set) and in the folders above it, where a
+// recursive batch started from a parent keeps its journal. Journals with no
+// stranded temp file belong to a batch that finished or never started
+// moving files, or one that is still running; they are left out. It only
+// reads: a batch running meanwhile must keep its journal.
+func FindRecovery(folder string, recursive bool) (Recovery, error) {
+	var rec Recovery
+	var journals, tmps []string
+
+	collect := func(dir, name string) {
+		switch {
+		case strings.HasPrefix(name, journalPrefix) && strings.HasSuffix(name, journalExt):
+			journals = append(journals, filepath.Join(dir, name))
+		case strings.HasPrefix(name, tmpPrefix):
+			tmps = append(tmps, filepath.Join(dir, name))
+		}
+	}
+
+	abs, err := filepath.Abs(folder)
+	if err != nil {
+		return rec, err
+	}
+	if recursive {
+		err = filepath.WalkDir(abs, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				if path == abs {
+					return err
+				}
+				rec.Errors = append(rec.Errors, err.Error())
+				if d != nil && d.IsDir() {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			if !d.IsDir() {
+				collect(filepath.Dir(path), d.Name())
+			}
+			return nil
+		})
+	} else {
+		var entries []os.DirEntry
+		entries, err = os.ReadDir(abs)
+		for _, e := range entries {
+			if !e.IsDir() {
+				collect(abs, e.Name())
+			}
+		}
+	}
+	if err != nil {
+		return rec, err
+	}
+	for dir := abs; filepath.Dir(dir) != dir; {
+		dir = filepath.Dir(dir)
+		matches, _ := filepath.Glob(filepath.Join(dir, journalPrefix+"*"+journalExt))
+		journals = append(journals, matches...)
+	}
+
+	known := map[string]bool{}
+	for _, path := range journals {
+		j, err := LoadJournal(path)
+		if err != nil {
+			rec.Errors = append(rec.Errors, err.Error())
+			continue
+		}
+		stranded := j.Stranded()
+		if len(stranded) == 0 {
+			continue
+		}
+		for _, e := range stranded {
+			known[e.Tmp] = true
+		}
+		rec.Journals = append(rec.Journals, j)
+	}
+	for _, t := range tmps {
+		if !known[t] {
+			rec.Orphans = append(rec.Orphans, t)
+		}
+	}
+	sort.Slice(rec.Journals, func(a, b int) bool { return rec.Journals[a].Started.Before(rec.Journals[b].Started) })
+	return rec, nil
+}
+
+// Recover finishes an interrupted batch (forward) or puts every file of it
+// back under its original name (back). Both run in two phases, like Apply,
+// so swaps are safe. Files that cannot be moved are left under their temp
+// name and reported as errors; the journal is kept until none are left.
+//
+// The journal is read again first: if it is gone, or has moved on to
+// another phase since it was found, the batch was recovered or is still
+// running elsewhere and nothing is moved.
+func (j *Journal) Recover(forward bool) ([]RenamePlanItem, error) {
+	cur, err := LoadJournal(j.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%s is gone: the batch was finished or recovered meanwhile", filepath.Base(j.Path))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if cur.Phase != j.Phase {
+		return nil, fmt.Errorf("%s: the batch has moved on (%s) since it was found; look again", filepath.Base(j.Path), phaseLabel(cur.Phase))
+	}
+	*j = *cur
+
+	item := func(e JournalEntry) RenamePlanItem {
+		from, to := e.Original, e.Final
+		if !forward {
+			from, to = e.Final, e.Original
+		}
+		return RenamePlanItem{
+			OldPath: from,
+			NewPath: to,
+			OldName: filepath.Base(from),
+			NewName: filepath.Base(to),
+			Status:  StatusRenamed,
+		}
+	}
+	var out []RenamePlanItem
+
+	// phase 1: move every file of the batch that still has to move, and is
+	// not under its temp name yet, onto it
+	for _, e := range j.Entries {
+		if exists(e.Tmp) {
+			continue
+		}
+		var from string
+		switch {
+		case forward && j.Phase == JournalStaging:
+			from = e.Original // never staged
+		case !forward && j.Phase == JournalFinalizing:
+			from = e.Final // already finished
+		default:
+			continue
+		}
+		if err := os.Rename(from, e.Tmp); err != nil && !errors.Is(err, os.ErrNotExist) {
+			it := item(e)
+			it.Status = StatusError
+			it.Reason = err.Error()
+			out = append(out, it)
+		}
+	}
+
+	// phase 2: temp → target, never over an existing file
+	for _, e := range j.Entries {
+		if !exists(e.Tmp) {
+			continue
+		}
+		it := item(e)
+		if _, err := os.Lstat(it.NewPath); err == nil {
+			it.Status = StatusError
+			it.Reason = fmt.Sprintf("target exists on disk; file left as %s", filepath.Base(e.Tmp))
+		} else if err := os.Rename(e.Tmp, it.NewPath); err != nil {
+			it.Status = StatusError
+			it.Reason = fmt.Sprintf("%v; file left as %s", err, filepath.Base(e.Tmp))
+		}
+		out = append(out, it)
+	}
+
+	if len(j.Stranded()) == 0 {
+		j.remove()
+	}
+	return out, nil
+}
+
+// staleJournalAge is how old a journal with nothing left to recover must be
+// before Apply deletes it; a younger one may belong to a batch another
+// RenForge is still running.
+const staleJournalAge = 24 * time.Hour
+
+// removeFinishedJournals deletes the journals in dir that a crashed batch
+// left behind after all its files were moved, or before any was.
+func removeFinishedJournals(dir string, now time.Time) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, journalPrefix) || !strings.HasSuffix(name, journalExt) {
+			continue
+		}
+		j, err := LoadJournal(filepath.Join(dir, name))
+		if err != nil || now.Sub(j.Started) < staleJournalAge || len(j.Stranded()) > 0 {
+			continue
+		}
+		j.remove()
+	}
+}
+
+/* -------------------- Messages -------------------- */
+
+// FormatRecovery describes what FindRecovery found; shared by the recovery
+// dialog and the CLI.
+func FormatRecovery(rec Recovery) string {
+	var b strings.Builder
+	for _, j := range rec.Journals {
+		stranded := j.Stranded()
+		b.WriteString(fmt.Sprintf("Interrupted rename from %s (%s): %d file(s) left under temporary names.\n",
+			j.Started.Format("2006-01-02 15:04:05"), phaseLabel(j.Phase), len(stranded)))
+		for _, e := range firstN(stranded, 20) {
+			b.WriteString(fmt.Sprintf(" - %s → %s\n", filepath.Base(e.Original), filepath.Base(e.Final)))
+		}
+		if len(stranded) > 20 {
+			b.WriteString(fmt.Sprintf(" ... and %d more\n", len(stranded)-20))
+		}
+		b.WriteString("\n")
+	}
+	writeList(&b, "Temporary files with no journal (rename them by hand):", rec.Orphans)
+	writeList(&b, "Could not read:", rec.Errors)
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+func phaseLabel(phase string) string {
+	if phase == JournalStaging {
+		return "stopped while moving files to temporary names"
+	}
+	return "stopped while giving files their new names"
+}
+
+/* -------------------- small helpers -------------------- */
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// commonDir is the deepest folder containing every dir.
+func commonDir(dirs []string) (string, error) {
+	var common string
+	for i, d := range dirs {
+		abs, err := filepath.Abs(d)
+		if err != nil {
+			return "", err
+		}
+		if i == 0 {
+			common = abs
+			continue
+		}
+		for !within(common, abs) {
+			parent := filepath.Dir(common)
+			if parent == common {
+				break
+			}
+			common = parent
+		}
+	}
+	return common, nil
+}
+
+// within reports whether path is dir or inside it.
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func relTo(dir, path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	if rel, err := filepath.Rel(dir, abs); err == nil {
+		return rel
+	}
+	return abs
+}
+
+// syncDir flushes a folder's entries (the renames) to disk. Not every
+// platform can open a folder for syncing; failures are ignored.
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		d.Close()
+	}
+}
@@ -0,0 +1,281 @@
+package engine
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// crash leaves dir the way an Apply of a ↔ b, c → d stopped during phase
+// would: the first moved entries of that phase are done.
+func crash(t *testing.T, dir, phase string, moved int) {
+	t.Helper()
+	writeFiles(t, dir, map[string]string{"a": "A", "b": "B", "c": "C"})
+	ts := time.Now().UnixNano()
+	var entries []JournalEntry
+	for i, it := range []RenamePlanItem{planItem(dir, "a", "b"), planItem(dir, "b", "a"), planItem(dir, "c", "d")} {
+		entries = append(entries, JournalEntry{Original: it.OldPath, Tmp: tmpPathFor(it.OldPath, ts, i), Final: it.NewPath})
+	}
+	j, err := startJournal(entries, ts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	move := func(from, to string) {
+		if err := os.Rename(from, to); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if phase == JournalStaging {
+		for _, e := range entries[:moved] {
+			move(e.Original, e.Tmp)
+		}
+		return
+	}
+	for _, e := range entries {
+		move(e.Original, e.Tmp)
+	}
+	j.Phase = JournalFinalizing
+	if err := j.save(); err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries[:moved] {
+		move(e.Tmp, e.Final)
+	}
+}
+
+func TestRecoverCrash(t *testing.T) {
+	tests := []struct {
+		phase string
+		moved int
+	}{
+		{JournalStaging, 1},
+		{JournalStaging, 2},
+		{JournalFinalizing, 0},
+		{JournalFinalizing, 1},
+		{JournalFinalizing, 2},
+	}
+	for _, tc := range tests {
+		for _, forward := range []bool{true, false} {
+			dir := t.TempDir()
+			crash(t, dir, tc.phase, tc.moved)
+
+			rec, err := FindRecovery(dir, false)
+			if err != nil || len(rec.Journals) != 1 || len(rec.Orphans) != 0 {
+				t.Fatalf("%s/%d: FindRecovery = %+v, %v", tc.phase, tc.moved, rec, err)
+			}
+			results, err := rec.Journals[0].Recover(forward)
+			if err != nil {
+				t.Fatalf("%s/%d: %v", tc.phase, tc.moved, err)
+			}
+			for _, it := range results {
+				if it.Status != StatusRenamed {
+					t.Errorf("%s/%d forward=%v: %s → %s: %s", tc.phase, tc.moved, forward, it.OldName, it.NewName, it.Reason)
+				}
+			}
+			want := map[string]string{"a": "B", "b": "A", "d": "C"}
+			if !forward {
+				want = map[string]string{"a": "A", "b": "B", "c": "C"}
+			}
+			if got := readDir(t, dir); !maps.Equal(got, want) {
+				t.Errorf("%s/%d forward=%v: folder = %v, want %v and no journal", tc.phase, tc.moved, forward, got, want)
+			}
+		}
+	}
+}
+
+func TestRecoverReloadsJournal(t *testing.T) {
+	dir := t.TempDir()
+	crash(t, dir, JournalStaging, 1)
+	rec, err := FindRecovery(dir, false)
+	if err != nil || len(rec.Journals) != 1 {
+		t.Fatalf("FindRecovery = %+v, %v", rec, err)
+	}
+	j := rec.Journals[0]
+	before := readDir(t, dir)
+
+	// the batch moved on to phase 2 after it was found
+	moved := *j
+	moved.Phase = JournalFinalizing
+	if err := moved.save(); err != nil {
+		t.Fatal(err)
+	}
+	before[filepath.Base(j.Path)] = readDir(t, dir)[filepath.Base(j.Path)]
+	if out, err := j.Recover(true); err == nil || len(out) != 0 {
+		t.Errorf("phase changed: Recover = %v, %v; want an error and no moves", out, err)
+	}
+	if got := readDir(t, dir); !maps.Equal(got, before) {
+		t.Errorf("phase changed: folder = %v, want it untouched", got)
+	}
+
+	// and was then recovered by someone else
+	if err := os.Remove(j.Path); err != nil {
+		t.Fatal(err)
+	}
+	delete(before, filepath.Base(j.Path))
+	if out, err := j.Recover(true); err == nil || !strings.Contains(err.Error(), "is gone") || len(out) != 0 {
+		t.Errorf("journal gone: Recover = %v, %v; want an error and no moves", out, err)
+	}
+	if got := readDir(t, dir); !maps.Equal(got, before) {
+		t.Errorf("journal gone: folder = %v, want it untouched", got)
+	}
+}
+
+func TestFindRecovery(t *testing.T) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "sub")
+	writeFiles(t, dir, map[string]string{
+		filepath.Join("sub", "x"):           "X",
+		filepath.Join("sub", tmpPrefix+"1"): "orphan",
+	})
+
+	// a batch over dir and sub, which keeps its journal in dir, that
+	// stranded a file in sub; and one that finished (or is still running)
+	// with nothing stranded
+	stuck, err := startJournal([]JournalEntry{
+		{Original: filepath.Join(dir, "v"), Tmp: filepath.Join(dir, tmpPrefix+"4"), Final: filepath.Join(dir, "u")},
+		{Original: filepath.Join(sub, "y"), Tmp: filepath.Join(sub, tmpPrefix+"2"), Final: filepath.Join(sub, "z")},
+	}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, sub, map[string]string{tmpPrefix + "2": "Y"})
+	done, err := startJournal([]JournalEntry{{
+		Original: filepath.Join(sub, "x"), Tmp: filepath.Join(sub, tmpPrefix+"3"), Final: filepath.Join(sub, "w"),
+	}}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, recursive := range []bool{false, true} {
+		for _, folder := range []string{sub, dir} {
+			rec, err := FindRecovery(folder, recursive)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rec.Journals) != 1 || rec.Journals[0].Path != stuck.Path {
+				t.Errorf("%s recursive=%v: journals %+v, want only %s", folder, recursive, rec.Journals, stuck.Path)
+			}
+			wantOrphans := 1
+			if folder == dir && !recursive {
+				wantOrphans = 0
+			}
+			if len(rec.Orphans) != wantOrphans {
+				t.Errorf("%s recursive=%v: orphans %v, want %d", folder, recursive, rec.Orphans, wantOrphans)
+			}
+		}
+	}
+	if !exists(done.Path) {
+		t.Error("FindRecovery deleted a journal with nothing stranded")
+	}
+}
+
+func TestFindRecoverySkipsUnreadable(t *testing.T) {
+	if runtime.GOOS == "windows" || os.Geteuid() == 0 {
+		t.Skip("folder permissions are not enforced")
+	}
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		filepath.Join("locked", "a"):         "A",
+		filepath.Join("open", tmpPrefix+"1"): "orphan",
+	})
+	locked := filepath.Join(dir, "locked")
+	if err := os.Chmod(locked, 0); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(locked, 0o755) })
+
+	rec, err := FindRecovery(dir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.Errors) != 1 || !strings.Contains(rec.Errors[0], "locked") {
+		t.Errorf("errors %v, want the locked folder", rec.Errors)
+	}
+	if len(rec.Orphans) != 1 {
+		t.Errorf("orphans %v, want the one in the readable folder", rec.Orphans)
+	}
+}
+
+func TestLoadJournal(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{filepath.Join("sub", "a"): "A"})
+	j, err := startJournal([]JournalEntry{{
+		Original: filepath.Join(dir, "sub", "a"), Tmp: filepath.Join(dir, "sub", tmpPrefix+"0"), Final: filepath.Join(dir, "b"),
+	}}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j.Path != filepath.Join(dir, "sub", journalPrefix+"5"+journalExt) {
+		t.Errorf("journal at %s, want it in the batch's folder", j.Path)
+	}
+	data, _ := os.ReadFile(j.Path)
+	if strings.Contains(string(data), dir) {
+		t.Errorf("journal stores absolute paths:\n%s", data)
+	}
+	got, err := LoadJournal(j.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Phase != JournalStaging || len(got.Entries) != 1 || got.Entries[0] != j.Entries[0] {
+		t.Errorf("loaded %+v, want %+v", got, j)
+	}
+
+	bad := map[string]string{
+		"not json":      `{"version":`,
+		"version":       `{"version": 2, "phase": "staging"}`,
+		"no version":    `{"phase": "staging"}`,
+		"unknown phase": `{"version": 1, "phase": "done"}`,
+	}
+	for name, content := range bad {
+		path := filepath.Join(dir, journalPrefix+"bad"+journalExt)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadJournal(path); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+	if _, err := LoadJournal(filepath.Join(dir, "missing.json")); !os.IsNotExist(err) {
+		t.Errorf("missing journal: %v, want not exist", err)
+	}
+}
+
+func TestStranded(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{tmpPrefix + "1": "A"})
+	j := &Journal{Entries: []JournalEntry{
+		{Original: filepath.Join(dir, "a"), Tmp: filepath.Join(dir, tmpPrefix+"1"), Final: filepath.Join(dir, "b")},
+		{Original: filepath.Join(dir, "c"), Tmp: filepath.Join(dir, tmpPrefix+"2"), Final: filepath.Join(dir, "d")},
+	}}
+	if got := j.Stranded(); len(got) != 1 || got[0] != j.Entries[0] {
+		t.Errorf("Stranded() = %v, want the entry whose temp file exists", got)
+	}
+}
+
+func TestApplyRemovesStaleJournals(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a": "A", tmpPrefix + "1": "stuck"})
+	old := time.Now().Add(-2 * staleJournalAge).UnixNano()
+	journal := func(ts int64, tmp string) string {
+		j, err := startJournal([]JournalEntry{{Original: filepath.Join(dir, "x"), Tmp: filepath.Join(dir, tmp), Final: filepath.Join(dir, "y")}}, ts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return j.Path
+	}
+	finished := journal(old, tmpPrefix+"gone")
+	stuck := journal(old+1, tmpPrefix+"1")
+	recent := journal(time.Now().UnixNano(), tmpPrefix+"gone")
+
+	Apply([]RenamePlanItem{planItem(dir, "a", "b")})
+	if exists(finished) {
+		t.Error("old journal with nothing stranded was kept")
+	}
+	if !exists(stuck) || !exists(recent) {
+		t.Error("a journal with stranded files, or a recent one, was deleted")
+	}
+}
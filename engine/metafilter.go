@@ -0,0 +1,292 @@
+package engine
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/* -------------------- Metadata filters -------------------- */
+
+// metaModes are the FilterModes that look at File.Info rather than the name.
+//
+//	size       "<10MB", ">=1.5 GB", "100KB-2MB", "0"
+//	modified   "today", "yesterday", "last 7 days", "older than 1 year",
+//	created    ">2024-01-01", "<=2024-06-30 18:00", "2024-01-01..2024-03-31"
+//	hidden     "yes" (or empty) / "no"
+//	read-only  "yes" (or empty) / "no"
+//	symlink    "yes" (or empty) / "no"
+var metaModes = map[string]bool{
+	"size": true, "modified": true, "created": true,
+	"hidden": true, "read-only": true, "symlink": true,
+}
+
+// flagModes are the yes/no metadata modes, which filter even when empty.
+var flagModes = map[string]bool{"hidden": true, "read-only": true, "symlink": true}
+
+// matchMeta checks one metadata rule against f. Like the name modes, an empty
+// size or date and a value ValidateFilter rejects match everything; a file
+// whose info couldn't be read matches no size or date.
+func matchMeta(f File, mode, val string, now time.Time) bool {
+	switch mode {
+	case "size":
+		if val == "" {
+			return true
+		}
+		lo, hi, err := parseSizeRange(val)
+		if err != nil {
+			return true
+		}
+		if f.Info == nil {
+			return false
+		}
+		n := f.Info.Size()
+		return n >= lo && (hi < 0 || n <= hi)
+	case "modified", "created":
+		if val == "" {
+			return true
+		}
+		from, to, err := parseDateRange(val, now)
+		if err != nil {
+			return true
+		}
+		if f.Info == nil {
+			return false
+		}
+		t := f.Info.ModTime()
+		if mode == "created" && !f.Created.IsZero() {
+			t = f.Created
+		}
+		return (from.IsZero() || !t.Before(from)) && (to.IsZero() || t.Before(to))
+	}
+
+	want, err := parseYesNo(val)
+	if err != nil {
+		return true
+	}
+	switch mode {
+	case "hidden":
+		return isHidden(f) == want
+	case "read-only":
+		return (f.Info != nil && f.Info.Mode().Perm()&0o222 == 0) == want
+	case "symlink":
+		return f.Symlink == want
+	}
+	return true
+}
+
+func validateMeta(mode, val string) error {
+	if val == "" {
+		return nil
+	}
+	var err error
+	switch mode {
+	case "size":
+		_, _, err = parseSizeRange(val)
+	case "modified", "created":
+		_, _, err = parseDateRange(val, time.Now())
+	default:
+		_, err = parseYesNo(val)
+	}
+	return err
+}
+
+// isHidden is a dot-file, or a file with the hidden attribute on Windows.
+func isHidden(f File) bool {
+	return strings.HasPrefix(filepath.Base(f.Path), ".") || hiddenAttr(f.Info)
+}
+
+func parseYesNo(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "", "yes", "y", "true", "1":
+		return true, nil
+	case "no", "n", "false", "0":
+		return false, nil
+	}
+	return false, fmt.Errorf("expected yes or no, got %q", s)
+}
+
+// parseSizeRange reads a size filter into inclusive bounds; hi is -1 when
+// there is no upper bound.
+func parseSizeRange(s string) (lo, hi int64, err error) {
+	s = strings.TrimSpace(s)
+	if a, b, ok := cutRange(s); ok {
+		if lo, err = parseSize(a); err != nil {
+			return 0, 0, err
+		}
+		if hi, err = parseSize(b); err != nil {
+			return 0, 0, err
+		}
+		if hi < lo {
+			return 0, 0, fmt.Errorf("size range %q ends before it starts", s)
+		}
+		return lo, hi, nil
+	}
+
+	op, rest := cutOperator(s)
+	n, err := parseSize(rest)
+	if err != nil {
+		return 0, 0, err
+	}
+	switch op {
+	case "<":
+		if n == 0 {
+			return 0, 0, fmt.Errorf("size %q matches no file", s)
+		}
+		return 0, n - 1, nil
+	case "<=":
+		return 0, n, nil
+	case ">":
+		return n + 1, -1, nil
+	case ">=":
+		return n, -1, nil
+	}
+	return n, n, nil
+}
+
+// parseSize reads "1.5 MB", "200k", "4096" (bytes). Units are binary:
+// 1 KB = 1024 bytes.
+func parseSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	num, unit := s, ""
+	if i >= 0 {
+		num, unit = s[:i], strings.TrimSpace(s[i:])
+	}
+	v, err := strconv.ParseFloat(num, 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("unknown size %q (try 500KB, 1.5MB or >10MB)", s)
+	}
+	shift, ok := sizeUnits[strings.TrimSuffix(unit, "IB")]
+	if !ok {
+		shift, ok = sizeUnits[strings.TrimSuffix(unit, "B")]
+	}
+	if !ok {
+		return 0, fmt.Errorf("unknown size unit %q", unit)
+	}
+	return int64(v * float64(int64(1)<<shift)), nil
+}
+
+var sizeUnits = map[string]uint{"": 0, "K": 10, "M": 20, "G": 30, "T": 40}
+
+// parseDateRange reads a date filter into [from, to); a zero time leaves
+// that side open. Dates are local; "last N days" counts back from now.
+func parseDateRange(s string, now time.Time) (from, to time.Time, err error) {
+	s = strings.ToLower(strings.Join(strings.Fields(s), " "))
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	switch s {
+	case "today":
+		return today, time.Time{}, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), today, nil
+	}
+	if rest, ok := strings.CutPrefix(s, "last "); ok {
+		start, err := countBack(rest, now)
+		return start, time.Time{}, err
+	}
+	if rest, ok := strings.CutPrefix(s, "older than "); ok {
+		end, err := countBack(rest, now)
+		return time.Time{}, end, err
+	}
+
+	if a, b, ok := cutRange(s); ok {
+		if from, _, err = parseDay(a, now); err != nil {
+			return from, to, err
+		}
+		if _, to, err = parseDay(b, now); err != nil {
+			return from, to, err
+		}
+		if !from.Before(to) {
+			return from, to, fmt.Errorf("date range %q ends before it starts", s)
+		}
+		return from, to, nil
+	}
+
+	op, rest := cutOperator(s)
+	start, end, err := parseDay(rest, now)
+	if err != nil {
+		return start, end, err
+	}
+	switch op {
+	case "<":
+		return time.Time{}, start, nil
+	case "<=":
+		return time.Time{}, end, nil
+	case ">":
+		return end, time.Time{}, nil
+	case ">=":
+		return start, time.Time{}, nil
+	}
+	return start, end, nil
+}
+
+// countBack reads "7 days", "2 weeks", "month" and returns that long before
+// now.
+func countBack(s string, now time.Time) (time.Time, error) {
+	n, unit := 1, s
+	if num, u, ok := strings.Cut(s, " "); ok {
+		v, err := strconv.Atoi(num)
+		if err != nil || v < 0 {
+			return time.Time{}, fmt.Errorf("unknown period %q (try 7 days)", s)
+		}
+		n, unit = v, u
+	}
+	switch strings.TrimSuffix(unit, "s") {
+	case "hour":
+		return now.Add(-time.Duration(n) * time.Hour), nil
+	case "day":
+		return now.AddDate(0, 0, -n), nil
+	case "week":
+		return now.AddDate(0, 0, -7*n), nil
+	case "month":
+		return now.AddDate(0, -n, 0), nil
+	case "year":
+		return now.AddDate(-n, 0, 0), nil
+	}
+	return time.Time{}, fmt.Errorf("unknown period %q (try hours, days, weeks, months or years)", unit)
+}
+
+var dayLayouts = []struct {
+	layout string
+	span   func(time.Time) time.Time
+}{
+	{"2006-01-02", func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }},
+	{"2006-01-02 15:04", func(t time.Time) time.Time { return t.Add(time.Minute) }},
+	{"2006-01-02t15:04:05", func(t time.Time) time.Time { return t.Add(time.Second) }},
+}
+
+// parseDay reads a date (or date and time) as the span [start, end) it
+// covers, so "<=2024-01-31" includes the whole of that day.
+func parseDay(s string, now time.Time) (start, end time.Time, err error) {
+	s = strings.TrimSpace(s)
+	for _, l := range dayLayouts {
+		if t, err := time.ParseInLocation(l.layout, s, now.Location()); err == nil {
+			return t, l.span(t), nil
+		}
+	}
+	return start, end, fmt.Errorf("unknown date %q (try 2024-01-31, last 7 days or >2024-01-01)", s)
+}
+
+// cutRange splits "a..b", and "a-b" when neither side looks like a date.
+func cutRange(s string) (a, b string, ok bool) {
+	if a, b, ok := strings.Cut(s, ".."); ok {
+		return strings.TrimSpace(a), strings.TrimSpace(b), true
+	}
+	if strings.Count(s, "-") == 1 {
+		a, b, _ := strings.Cut(s, "-")
+		return strings.TrimSpace(a), strings.TrimSpace(b), true
+	}
+	return "", "", false
+}
+
+func cutOperator(s string) (op, rest string) {
+	for _, op := range []string{"<=", ">=", "<", ">", "="} {
+		if rest, ok := strings.CutPrefix(s, op); ok {
+			return op, strings.TrimSpace(rest)
+		}
+	}
+	return "", s
+}
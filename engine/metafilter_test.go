@@ -0,0 +1,167 @@
+package engine
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseSizeRange(t *testing.T) {
+	tests := []struct {
+		in     string
+		lo, hi int64
+		bad    bool
+	}{
+		{in: "0", lo: 0, hi: 0},
+		{in: "4096", lo: 4096, hi: 4096},
+		{in: "<10MB", lo: 0, hi: 10<<20 - 1},
+		{in: "<= 1k", lo: 0, hi: 1024},
+		{in: ">=1.5 GB", lo: 3 << 29, hi: -1},
+		{in: "> 200KiB", lo: 200<<10 + 1, hi: -1},
+		{in: "100KB-2MB", lo: 100 << 10, hi: 2 << 20},
+		{in: "1k..1k", lo: 1024, hi: 1024},
+		{in: "2MB..1MB", bad: true},
+		{in: "10 XB", bad: true},
+		{in: "big", bad: true},
+		{in: "-5", bad: true},
+		{in: "<0", bad: true},
+		{in: "<0.1", bad: true},
+	}
+	for _, tc := range tests {
+		lo, hi, err := parseSizeRange(tc.in)
+		switch {
+		case tc.bad && err == nil:
+			t.Errorf("%q: got %d..%d, want an error", tc.in, lo, hi)
+		case !tc.bad && err != nil:
+			t.Errorf("%q: %v", tc.in, err)
+		case !tc.bad && (lo != tc.lo || hi != tc.hi):
+			t.Errorf("%q: got %d..%d, want %d..%d", tc.in, lo, hi, tc.lo, tc.hi)
+		}
+	}
+}
+
+func TestParseDateRange(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 30, 0, 0, time.Local)
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.Local) }
+	var open time.Time
+
+	tests := []struct {
+		in       string
+		from, to time.Time
+		bad      bool
+	}{
+		{in: "today", from: day(2024, 6, 15), to: open},
+		{in: "Yesterday", from: day(2024, 6, 14), to: day(2024, 6, 15)},
+		{in: "last 7 days", from: now.AddDate(0, 0, -7), to: open},
+		{in: "last  week", from: now.AddDate(0, 0, -7), to: open},
+		{in: "older than 1 year", from: open, to: now.AddDate(-1, 0, 0)},
+		{in: "2024-01-31", from: day(2024, 1, 31), to: day(2024, 2, 1)},
+		{in: ">2024-01-01", from: day(2024, 1, 2), to: open},
+		{in: ">=2024-01-01", from: day(2024, 1, 1), to: open},
+		{in: "<2024-01-01", from: open, to: day(2024, 1, 1)},
+		{in: "<=2024-06-30 18:00", from: open, to: time.Date(2024, 6, 30, 18, 1, 0, 0, time.Local)},
+		{in: "2024-01-01..2024-03-31", from: day(2024, 1, 1), to: day(2024, 4, 1)},
+		{in: "2024-03-31..2024-01-01", bad: true},
+		{in: "last 7 fortnights", bad: true},
+		{in: "soon", bad: true},
+	}
+	for _, tc := range tests {
+		from, to, err := parseDateRange(tc.in, now)
+		switch {
+		case tc.bad && err == nil:
+			t.Errorf("%q: got [%v, %v), want an error", tc.in, from, to)
+		case !tc.bad && err != nil:
+			t.Errorf("%q: %v", tc.in, err)
+		case !tc.bad && (!from.Equal(tc.from) || !to.Equal(tc.to)):
+			t.Errorf("%q: got [%v, %v), want [%v, %v)", tc.in, from, to, tc.from, tc.to)
+		}
+	}
+}
+
+func TestParseYesNo(t *testing.T) {
+	for _, in := range []string{"", "yes", "Y", "TRUE", "1"} {
+		if v, err := parseYesNo(in); err != nil || !v {
+			t.Errorf("%q: %v, %v; want yes", in, v, err)
+		}
+	}
+	for _, in := range []string{"no", "N", "False", "0"} {
+		if v, err := parseYesNo(in); err != nil || v {
+			t.Errorf("%q: %v, %v; want no", in, v, err)
+		}
+	}
+	for _, in := range []string{"maybe", "yes please", "2"} {
+		if _, err := parseYesNo(in); err == nil {
+			t.Errorf("%q: no error", in)
+		}
+	}
+}
+
+// modeInfo is a fakeInfo with a permission mode.
+type modeInfo struct {
+	fakeInfo
+	mode fs.FileMode
+}
+
+func (fi modeInfo) Mode() fs.FileMode { return fi.mode }
+
+func TestMatchMeta(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.Local)
+	mod := time.Date(2024, 6, 10, 9, 0, 0, 0, time.Local)
+	created := time.Date(2023, 1, 5, 9, 0, 0, 0, time.Local)
+	dir := filepath.FromSlash("/d")
+
+	plain := File{Path: filepath.Join(dir, "a.txt"), Info: fakeInfo{name: "a.txt", size: 2048, mod: mod}, Created: created}
+	uncreated := File{Path: filepath.Join(dir, "b.txt"), Info: fakeInfo{name: "b.txt", mod: mod}}
+	dot := File{Path: filepath.Join(dir, ".env"), Info: fakeInfo{name: ".env"}}
+	locked := File{Path: filepath.Join(dir, "r.txt"), Info: modeInfo{fakeInfo{name: "r.txt"}, 0o444}}
+	link := File{Path: filepath.Join(dir, "l.txt"), Info: fakeInfo{name: "l.txt"}, Symlink: true}
+	gone := File{Path: filepath.Join(dir, "gone.txt")}
+
+	tests := []struct {
+		f         File
+		mode, val string
+		want      bool
+	}{
+		{plain, "size", ">1KB", true},
+		{plain, "size", "<2KB", false},
+		{plain, "size", "1KB-2KB", true},
+		{plain, "size", "", true},
+		{plain, "size", "huge", true}, // invalid values filter nothing
+		{gone, "size", ">0", false},
+		{plain, "modified", "last 7 days", true},
+		{plain, "modified", "older than 1 week", false},
+		{plain, "created", "<2024-01-01", true},
+		{plain, "created", "last 7 days", false},
+		{uncreated, "created", "last 7 days", true}, // no birth time: modified
+		{gone, "created", "<2024-01-01", false},
+		{dot, "hidden", "", true},
+		{plain, "hidden", "yes", false},
+		{plain, "hidden", "no", true},
+		{locked, "read-only", "yes", true},
+		{plain, "read-only", "yes", false},
+		{gone, "read-only", "no", true},
+		{link, "symlink", "", true},
+		{plain, "symlink", "no", true},
+		{plain, "symlink", "perhaps", true},
+	}
+	for _, tc := range tests {
+		if got := matchMeta(tc.f, tc.mode, tc.val, now); got != tc.want {
+			t.Errorf("%s %s %q = %v, want %v", filepath.Base(tc.f.Path), tc.mode, tc.val, got, tc.want)
+		}
+	}
+}
+
+func TestScanRecordsCreated(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.txt": "A"})
+	files, err := ListAllFiles(dir, false)
+	if err != nil || len(files) != 1 {
+		t.Fatalf("ListAllFiles = %v, %v", files, err)
+	}
+	fi, _ := os.Stat(filepath.Join(dir, "a.txt"))
+	if want := createdTime(files[0].Path, fi); !files[0].Created.Equal(want) || want.IsZero() {
+		t.Errorf("Created = %v, want %v", files[0].Created, want)
+	}
+}
@@ -0,0 +1,221 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+/* -------------------- Numbering -------------------- */
+
+// PadAuto sizes the zero padding from the largest number in the sequence.
+const PadAuto = -1
+
+// Where a numbering step puts the number. NumAppend goes after the whole
+// name, extension included ("a.jpg_001"); the Append step's place, before
+// the extension, is NumBeforeExt.
+const (
+	NumPrepend   = "prepend"
+	NumAppend    = "after extension"
+	NumBeforeExt = "before extension"
+)
+
+// NumberPositions lists the supported positions in UI order.
+var NumberPositions = []string{NumPrepend, NumAppend, NumBeforeExt}
+
+const (
+	SortByName     = "name"
+	SortByNatural  = "natural name"
+	SortByModified = "modified time"
+	SortBySize     = "size"
+)
+
+// NumberSortKeys lists the supported orderings in UI order.
+var NumberSortKeys = []string{SortByName, SortByNatural, SortByModified, SortBySize}
+
+type NumberingOptions struct {
+	Start     int    `json:"start"`
+	Increment int    `json:"increment"`  // 0 is treated as 1
+	Padding   int    `json:"padding"`    // minimum digits; 0 for none, PadAuto from the total count
+	Position  string `json:"position"`   // NumPrepend, NumAppend or NumBeforeExt
+	SortBy    string `json:"sort_by"`    // SortByName, SortByNatural, SortByModified or SortBySize
+	PerFolder bool   `json:"per_folder"` // restart the counter in every directory
+}
+
+// UnmarshalJSON also reads the position "append", which is what NumAppend
+// was called before it said where the number goes.
+func (n *NumberingOptions) UnmarshalJSON(data []byte) error {
+	type plain NumberingOptions
+	if err := json.Unmarshal(data, (*plain)(n)); err != nil {
+		return err
+	}
+	if n.Position == "append" {
+		n.Position = NumAppend
+	}
+	return nil
+}
+
+// DefaultNumbering is the configuration a new numbering step starts with.
+func DefaultNumbering() NumberingOptions {
+	return NumberingOptions{
+		Start:     1,
+		Increment: 1,
+		Padding:   PadAuto,
+		Position:  NumBeforeExt,
+		SortBy:    SortByNatural,
+	}
+}
+
+// counter is the number one file gets from a numbering step.
+type counter struct {
+	value int
+	width int // zero padding
+}
+
+// assignCounters orders files by opts.SortBy (per directory when
+// opts.PerFolder is set) and hands out numbers in that order.
+func assignCounters(files []string, opts NumberingOptions, info func(string) os.FileInfo) map[string]counter {
+	inc := opts.Increment
+	if inc == 0 {
+		inc = 1
+	}
+
+	groups := map[string][]string{}
+	var groupOrder []string
+	for _, p := range files {
+		key := ""
+		if opts.PerFolder {
+			key = filepath.Dir(p)
+		}
+		if _, ok := groups[key]; !ok {
+			groupOrder = append(groupOrder, key)
+		}
+		groups[key] = append(groups[key], p)
+	}
+
+	out := make(map[string]counter, len(files))
+	for _, key := range groupOrder {
+		group := append([]string(nil), groups[key]...)
+		sortFiles(group, opts.SortBy, info)
+
+		width := opts.Padding
+		if width == PadAuto {
+			last := opts.Start + (len(group)-1)*inc
+			width = max(digits(opts.Start), digits(last))
+		}
+		for i, p := range group {
+			out[p] = counter{value: opts.Start + i*inc, width: width}
+		}
+	}
+	return out
+}
+
+func sortFiles(files []string, key string, info func(string) os.FileInfo) {
+	byName := func(a, b string) bool {
+		return filepath.Base(a) < filepath.Base(b) || (filepath.Base(a) == filepath.Base(b) && a < b)
+	}
+
+	switch key {
+	case SortByNatural:
+		sort.SliceStable(files, func(i, j int) bool {
+			if c := NaturalCompare(filepath.Base(files[i]), filepath.Base(files[j])); c != 0 {
+				return c < 0
+			}
+			return files[i] < files[j]
+		})
+	case SortByModified:
+		sort.SliceStable(files, func(i, j int) bool {
+			ti, tj := modTime(info(files[i])), modTime(info(files[j]))
+			if !ti.Equal(tj) {
+				return ti.Before(tj)
+			}
+			return byName(files[i], files[j])
+		})
+	case SortBySize:
+		sort.SliceStable(files, func(i, j int) bool {
+			si, sj := fileSize(info(files[i])), fileSize(info(files[j]))
+			if si != sj {
+				return si < sj
+			}
+			return byName(files[i], files[j])
+		})
+	default:
+		sort.SliceStable(files, func(i, j int) bool { return byName(files[i], files[j]) })
+	}
+}
+
+// applyNumber places the counter c into name according to opts.
+func applyNumber(name string, sep string, opts NumberingOptions, c counter) string {
+	num := strconv.Itoa(c.value)
+	if c.width > 0 {
+		num = fmt.Sprintf("%0*d", c.width, c.value)
+	}
+
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	switch opts.Position {
+	case NumPrepend:
+		return num + sep + name
+	case NumAppend:
+		return name + sep + num
+	default:
+		return base + sep + num + ext
+	}
+}
+
+// NaturalCompare orders names case-insensitively, comparing runs of digits
+// by numeric value so "file2" sorts before "file10".
+func NaturalCompare(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	i, j := 0, 0
+	for i < len(ra) && j < len(rb) {
+		if unicode.IsDigit(ra[i]) && unicode.IsDigit(rb[j]) {
+			si := i
+			for i < len(ra) && unicode.IsDigit(ra[i]) {
+				i++
+			}
+			sj := j
+			for j < len(rb) && unicode.IsDigit(rb[j]) {
+				j++
+			}
+			na := strings.TrimLeft(string(ra[si:i]), "0")
+			nb := strings.TrimLeft(string(rb[sj:j]), "0")
+			if len(na) != len(nb) {
+				return cmpInt(len(na), len(nb))
+			}
+			if na != nb {
+				return strings.Compare(na, nb)
+			}
+			continue
+		}
+		ca, cb := unicode.ToLower(ra[i]), unicode.ToLower(rb[j])
+		if ca != cb {
+			return cmpInt(int(ca), int(cb))
+		}
+		i++
+		j++
+	}
+	return cmpInt(len(ra)-i, len(rb)-j)
+}
+
+func cmpInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func digits(n int) int {
+	if n < 0 {
+		n = -n
+	}
+	return len(strconv.Itoa(n))
+}
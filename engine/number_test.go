@@ -0,0 +1,132 @@
+package engine
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAssignCounters(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	infos := map[string]os.FileInfo{}
+	var files []string
+	// name, size, minutes after base
+	for _, f := range []struct {
+		path      string
+		size, min int64
+	}{
+		{"/a/img10.jpg", 300, 1},
+		{"/a/img2.jpg", 100, 3},
+		{"/a/IMG1.jpg", 200, 2},
+		{"/b/x.jpg", 50, 0},
+		{"/b/y.jpg", 50, 0},
+	} {
+		p := filepath.FromSlash(f.path)
+		files = append(files, p)
+		infos[p] = fakeInfo{name: filepath.Base(p), size: f.size, mod: base.Add(time.Duration(f.min) * time.Minute)}
+	}
+	info := func(p string) os.FileInfo { return infos[p] }
+
+	tests := []struct {
+		name string
+		opts NumberingOptions
+		want map[string]counter // by base name
+	}{
+		{"by name", NumberingOptions{Start: 1, Increment: 1, SortBy: SortByName}, map[string]counter{
+			"IMG1.jpg": {1, 0}, "img10.jpg": {2, 0}, "img2.jpg": {3, 0}, "x.jpg": {4, 0}, "y.jpg": {5, 0},
+		}},
+		{"natural", NumberingOptions{Start: 1, Increment: 1, SortBy: SortByNatural}, map[string]counter{
+			"IMG1.jpg": {1, 0}, "img2.jpg": {2, 0}, "img10.jpg": {3, 0}, "x.jpg": {4, 0}, "y.jpg": {5, 0},
+		}},
+		{"by modified time", NumberingOptions{Start: 1, Increment: 1, SortBy: SortByModified}, map[string]counter{
+			"x.jpg": {1, 0}, "y.jpg": {2, 0}, "img10.jpg": {3, 0}, "IMG1.jpg": {4, 0}, "img2.jpg": {5, 0},
+		}},
+		{"by size, ties by name", NumberingOptions{Start: 1, Increment: 1, SortBy: SortBySize}, map[string]counter{
+			"x.jpg": {1, 0}, "y.jpg": {2, 0}, "img2.jpg": {3, 0}, "IMG1.jpg": {4, 0}, "img10.jpg": {5, 0},
+		}},
+		{"start, increment, padding", NumberingOptions{Start: 10, Increment: 5, Padding: 4, SortBy: SortByNatural}, map[string]counter{
+			"IMG1.jpg": {10, 4}, "img2.jpg": {15, 4}, "img10.jpg": {20, 4}, "x.jpg": {25, 4}, "y.jpg": {30, 4},
+		}},
+		{"auto padding from the last number", NumberingOptions{Start: 8, Increment: 1, Padding: PadAuto, SortBy: SortByNatural}, map[string]counter{
+			"IMG1.jpg": {8, 2}, "img2.jpg": {9, 2}, "img10.jpg": {10, 2}, "x.jpg": {11, 2}, "y.jpg": {12, 2},
+		}},
+		{"auto padding counting down", NumberingOptions{Start: 100, Increment: -30, Padding: PadAuto, SortBy: SortByNatural}, map[string]counter{
+			"IMG1.jpg": {100, 3}, "img2.jpg": {70, 3}, "img10.jpg": {40, 3}, "x.jpg": {10, 3}, "y.jpg": {-20, 3},
+		}},
+		{"zero increment counts by one", NumberingOptions{Start: 1, SortBy: SortByNatural}, map[string]counter{
+			"IMG1.jpg": {1, 0}, "img2.jpg": {2, 0}, "img10.jpg": {3, 0}, "x.jpg": {4, 0}, "y.jpg": {5, 0},
+		}},
+		{"per folder", NumberingOptions{Start: 1, Increment: 1, Padding: PadAuto, SortBy: SortByNatural, PerFolder: true}, map[string]counter{
+			"IMG1.jpg": {1, 1}, "img2.jpg": {2, 1}, "img10.jpg": {3, 1}, "x.jpg": {1, 1}, "y.jpg": {2, 1},
+		}},
+	}
+	for _, tc := range tests {
+		got := assignCounters(files, tc.opts, info)
+		for _, p := range files {
+			if c := got[p]; c != tc.want[filepath.Base(p)] {
+				t.Errorf("%s: %s gets %+v, want %+v", tc.name, filepath.Base(p), c, tc.want[filepath.Base(p)])
+			}
+		}
+	}
+}
+
+func TestApplyNumber(t *testing.T) {
+	tests := []struct {
+		name, sep, pos string
+		c              counter
+		want           string
+	}{
+		{"photo.jpg", "_", NumBeforeExt, counter{7, 3}, "photo_007.jpg"},
+		{"photo.jpg", "-", NumPrepend, counter{7, 0}, "7-photo.jpg"},
+		{"photo.jpg", "_", NumAppend, counter{12, 3}, "photo.jpg_012"},
+		{"archive.tar.gz", "_", NumBeforeExt, counter{1, 0}, "archive.tar_1.gz"},
+		{"README", " ", NumBeforeExt, counter{2, 2}, "README 02"},
+		{"photo.jpg", "_", "", counter{1, 0}, "photo_1.jpg"},
+	}
+	for _, tc := range tests {
+		got := applyNumber(tc.name, tc.sep, NumberingOptions{Position: tc.pos}, tc.c)
+		if got != tc.want {
+			t.Errorf("%q %s: got %q, want %q", tc.name, tc.pos, got, tc.want)
+		}
+	}
+}
+
+func TestNumberingPositionJSON(t *testing.T) {
+	for in, want := range map[string]string{
+		`{"position": "append"}`:           NumAppend,
+		`{"position": "after extension"}`:  NumAppend,
+		`{"position": "before extension"}`: NumBeforeExt,
+		`{"position": "prepend"}`:          NumPrepend,
+	} {
+		var n NumberingOptions
+		if err := json.Unmarshal([]byte(in), &n); err != nil || n.Position != want {
+			t.Errorf("%s: got %q, %v; want %q", in, n.Position, err, want)
+		}
+	}
+}
+
+func TestNaturalCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"file2", "file10", -1},
+		{"file10", "file2", 1},
+		{"File2", "file2", 0},
+		{"file02", "file2", 0},
+		{"file2a", "file2b", -1},
+		{"a", "ab", -1},
+		{"", "", 0},
+		{"img9.jpg", "img10.jpg", -1},
+		{"v1.10", "v1.9", 1},
+		{"99999999999999999999", "100000000000000000000", -1},
+		{"Émile", "emile", 1},
+	}
+	for _, tc := range tests {
+		if got := NaturalCompare(tc.a, tc.b); got != tc.want {
+			t.Errorf("NaturalCompare(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
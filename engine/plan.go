@@ -0,0 +1,401 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+/* -------------------- Plan -------------------- */
+
+type PlanSummary struct {
+	Total        int
+	OkCount      int
+	Unchanged    int
+	Ignored      int // undo log rows that were never renamed
+	Invalid      []string
+	Missing      []string
+	Duplicate    []string
+	TargetExists []string
+	Resolved     []string // collisions settled by the collision policy
+
+	Normalization []string // renamed, but look identical to another name
+}
+
+// Plan validates the rename of every path in selected through opts.Steps.
+// Problem items are kept in the plan with Status "skip" and a Reason.
+func Plan(selected []string, opts Options) ([]RenamePlanItem, PlanSummary) {
+	return PlanBatch(NewBatch(selected, opts.Listing, opts.Steps, opts.Profile), selected, opts)
+}
+
+// PlanBatch is Plan with the names taken from an existing batch instead of
+// opts.Steps, so the preview doesn't compute them twice.
+func PlanBatch(batch *Batch, selected []string, opts Options) ([]RenamePlanItem, PlanSummary) {
+	items := make([]RenamePlanItem, 0, len(selected))
+
+	var sum PlanSummary
+	sum.Total = len(selected)
+
+	for _, oldPath := range selected {
+		oldName := filepath.Base(oldPath)
+		newName := batch.Name(oldPath)
+
+		it := RenamePlanItem{
+			OldPath: oldPath,
+			NewPath: filepath.Join(filepath.Dir(oldPath), newName),
+			OldName: oldName,
+			NewName: newName,
+			Status:  StatusOK,
+		}
+
+		if newName == oldName {
+			sum.Unchanged++
+			it.Status = StatusSkip
+			it.Reason = ReasonUnchanged
+		} else if reason := InvalidPathReason(filepath.Dir(oldPath), newName, opts.Profile); reason != "" {
+			sum.Invalid = append(sum.Invalid, fmt.Sprintf("%s → %s (%s)", oldName, newName, reason))
+			it.Status = StatusSkip
+			it.Reason = "invalid: " + reason
+		}
+		items = append(items, it)
+	}
+
+	keys := newPathKeys(opts.CaseFS, opts.Listing)
+	checkConflicts(items, &sum, opts.Collision, keys)
+	normalizationWarnings(items, &sum, keys)
+	return items, sum
+}
+
+// Reasons Plan and checkConflicts give for skipping an item. Only
+// ReasonUnchanged is not a problem: the file already has its new name.
+const (
+	ReasonUnchanged    = "unchanged"
+	ReasonMissing      = "missing: source no longer exists"
+	ReasonDuplicate    = "conflict: duplicate preview name"
+	ReasonTargetExists = "conflict: target exists on disk"
+)
+
+// checkConflicts runs the checks shared by Plan and PlanUndo on every item
+// that is still "ok": the source must exist, and no item may end up at a
+// path that is taken once the whole plan has run, whether by another item or
+// by a file that stays on disk. A target that is itself renamed away by the
+// plan (a → b, b → c, or the swap a → b, b → a) is free. Paths are compared
+// and looked up through keys. Taken targets are settled by policy (see
+// CollisionPolicies); survivors are counted in sum.OkCount.
+func checkConflicts(items []RenamePlanItem, sum *PlanSummary, policy string, keys *pathKeys) {
+	for i := range items {
+		if items[i].Status == StatusOK {
+			if keys.scan != nil {
+				if !keys.scanned("", items[i].OldPath) {
+					items[i].Status = StatusSkip
+					items[i].Reason = ReasonMissing
+				}
+			} else if _, err := os.Lstat(items[i].OldPath); err != nil {
+				items[i].Status = StatusSkip
+				items[i].Reason = ReasonMissing
+			}
+		}
+	}
+
+	// disk doesn't change while planning; stat each target once
+	onDisk := make([]bool, len(items))
+	for i, it := range items {
+		onDisk[i] = it.Status == StatusOK && keys.exists(it.OldPath, it.NewPath)
+	}
+
+	switch policy {
+	case CollisionCounter:
+		resolveCounters(items, onDisk, " ", keys)
+	case CollisionUnderscore:
+		resolveCounters(items, onDisk, "_", keys)
+	default:
+		backup := resolveContests(items, onDisk, policy, keys)
+		assignBackups(items, backup, policy, keys)
+	}
+
+	for _, it := range items {
+		label := fmt.Sprintf("%s → %s", it.OldName, it.NewName)
+		switch {
+		case strings.HasPrefix(it.Reason, ReasonCollision):
+			sum.Resolved = append(sum.Resolved, fmt.Sprintf("%s (%s)", label, strings.TrimPrefix(it.Reason, ReasonCollision)))
+		case it.Reason == ReasonMissing:
+			sum.Missing = append(sum.Missing, label)
+		case it.Reason == ReasonDuplicate:
+			sum.Duplicate = append(sum.Duplicate, label)
+		case it.Reason == ReasonTargetExists:
+			sum.TargetExists = append(sum.TargetExists, label)
+		}
+		if it.Status == StatusOK {
+			sum.OkCount++
+		}
+	}
+}
+
+// TargetExists reports whether renaming oldPath to newPath would collide with
+// another file on disk. On case-insensitive volumes a case-only rename
+// (report.pdf → Report.PDF) finds the source itself, which is not a
+// collision: Apply's two-phase rename handles it.
+func TargetExists(oldPath, newPath string) bool {
+	target, err := os.Stat(newPath)
+	if err != nil {
+		return false
+	}
+	src, err := os.Stat(oldPath)
+	return err != nil || !os.SameFile(src, target)
+}
+
+/* -------------------- Apply -------------------- */
+
+// rename is os.Rename; tests swap it to make a given move fail.
+var rename = os.Rename
+
+// Apply uses a two-phase rename to safely handle circular renames
+// (e.g. a→b and b→a). Phase 1 moves every file to a temp name; phase 2
+// moves each temp name to its final destination. A file being overwritten
+// (see RenamePlanItem.BackupPath) takes the same route to its backup name,
+// ahead of the item replacing it. A journal is written and fsynced before
+// each phase so an interrupted batch can be recovered (see FindRecovery);
+// it is removed once the batch is done, unless a failed item's file had to
+// be left under its temp name. Journals an earlier crash left with nothing
+// to recover are cleaned up from the same folder.
+func Apply(plan []RenamePlanItem) []RenamePlanItem {
+	out := make([]RenamePlanItem, len(plan))
+	copy(out, plan)
+
+	type move struct {
+		idx    int // plan item the move belongs to
+		backup bool
+		entry  JournalEntry
+	}
+	var moves []move
+
+	ts := time.Now().UnixNano()
+
+	for i := range out {
+		if out[i].Status != StatusOK {
+			continue
+		}
+		if out[i].BackupPath != "" {
+			moves = append(moves, move{i, true, JournalEntry{
+				Original: out[i].NewPath,
+				Tmp:      tmpPathFor(out[i].NewPath, ts, len(out)+i),
+				Final:    out[i].BackupPath,
+			}})
+		}
+		moves = append(moves, move{i, false, JournalEntry{
+			Original: out[i].OldPath,
+			Tmp:      tmpPathFor(out[i].OldPath, ts, i),
+			Final:    out[i].NewPath,
+		}})
+	}
+	if len(moves) == 0 {
+		return out
+	}
+
+	entries := make([]JournalEntry, len(moves))
+	for k, m := range moves {
+		entries[k] = m.entry
+	}
+	journal, err := startJournal(entries, ts)
+	if err != nil {
+		for _, m := range moves {
+			out[m.idx].Status = StatusError
+			out[m.idx].Reason = err.Error()
+		}
+		return out
+	}
+	for k := range moves {
+		moves[k].entry = entries[k] // now absolute
+	}
+
+	fail := func(i int, err error) {
+		if out[i].Status != StatusError {
+			out[i].Status = StatusError
+			out[i].Reason = err.Error()
+		}
+	}
+
+	var staged []move
+	for _, m := range moves {
+		if out[m.idx].Status == StatusError {
+			continue // its backup failed: never overwrite
+		}
+		if err := rename(m.entry.Original, m.entry.Tmp); err != nil {
+			if m.backup {
+				err = fmt.Errorf("backup: %w", err)
+			}
+			fail(m.idx, err)
+			continue
+		}
+		staged = append(staged, m)
+	}
+	done := make([]JournalEntry, len(staged))
+	for k, m := range staged {
+		done[k] = m.entry
+	}
+	syncDirs(done)
+
+	journal.Phase = JournalFinalizing
+	journal.Entries = done
+	if err := journal.save(); err != nil {
+		// without a record of phase 2 a crash could not be recovered;
+		// put everything back instead
+		for _, m := range staged {
+			fail(m.idx, err)
+			if err := rename(m.entry.Tmp, m.entry.Original); err != nil {
+				out[m.idx].Reason += fmt.Sprintf("; %v, file left as %s", err, m.entry.Tmp)
+			}
+		}
+		journal.remove()
+		return out
+	}
+
+	// restore puts a staged file back under its original name and takes it
+	// out of the journal. If another file has moved into that name since
+	// (x → y went ahead, y's own rename failed), or the move fails, the file
+	// stays under its temp name and in the journal for FindRecovery, and
+	// the item says where it is.
+	restore := func(m move) {
+		if _, err := os.Lstat(m.entry.Original); err == nil {
+			out[m.idx].Reason += fmt.Sprintf("; %s is taken, file left as %s", filepath.Base(m.entry.Original), m.entry.Tmp)
+			return
+		}
+		if err := rename(m.entry.Tmp, m.entry.Original); err != nil {
+			out[m.idx].Reason += fmt.Sprintf("; %v, file left as %s", err, m.entry.Tmp)
+			return
+		}
+		journal.Entries = slices.DeleteFunc(journal.Entries, func(e JournalEntry) bool {
+			return e == m.entry
+		})
+		_ = journal.save()
+	}
+
+	for _, m := range staged {
+		if out[m.idx].Status == StatusError {
+			restore(m) // its backup failed in phase 2
+			continue
+		}
+		// phase 1 emptied every target the plan takes over, so a file there
+		// now was put back by restore (or created meanwhile): never replace it
+		if _, err := os.Lstat(m.entry.Final); err == nil {
+			fail(m.idx, errors.New(ReasonTargetExists))
+			restore(m)
+			continue
+		}
+		if err := rename(m.entry.Tmp, m.entry.Final); err != nil {
+			if m.backup {
+				err = fmt.Errorf("backup: %w", err)
+			}
+			fail(m.idx, err)
+			restore(m)
+			continue
+		}
+		if !m.backup {
+			out[m.idx].Status = StatusRenamed
+		}
+	}
+	syncDirs(done)
+
+	if len(journal.Stranded()) == 0 {
+		journal.remove()
+	}
+	removeFinishedJournals(filepath.Dir(journal.Path), time.Now())
+	return out
+}
+
+// tmpPathFor is the phase-1 name of plan item i, next to its source.
+func tmpPathFor(oldPath string, ts int64, i int) string {
+	return filepath.Join(filepath.Dir(oldPath), fmt.Sprintf(".renforge_tmp_%d_%d", ts, i))
+}
+
+// syncDirs flushes the folders of entries once each.
+func syncDirs(entries []JournalEntry) {
+	seen := map[string]bool{}
+	for _, e := range entries {
+		dir := filepath.Dir(e.Original)
+		if !seen[dir] {
+			seen[dir] = true
+			syncDir(dir)
+		}
+	}
+}
+
+// MarkDryRun flags every "ok" item as "dry-run" in place.
+func MarkDryRun(plan []RenamePlanItem) {
+	for i := range plan {
+		if plan[i].Status == StatusOK {
+			plan[i].Status = StatusDryRun
+		}
+	}
+}
+
+/* -------------------- Messages -------------------- */
+
+// FormatSummary describes a plan; shared by the confirm dialog and the CLI.
+func FormatSummary(sum PlanSummary) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("You are about to process %d file(s).\n", sum.Total))
+	b.WriteString(fmt.Sprintf("Will rename: %d\n", sum.OkCount))
+	b.WriteString(fmt.Sprintf("Unchanged (skipped): %d\n", sum.Unchanged))
+	if sum.Ignored > 0 {
+		b.WriteString(fmt.Sprintf("Log rows that were never renamed (ignored): %d\n", sum.Ignored))
+	}
+	b.WriteString("\n")
+
+	writeList(&b, "Invalid names (skipped):", sum.Invalid)
+	writeList(&b, "Source file missing (skipped):", sum.Missing)
+	writeList(&b, "Duplicate preview conflicts (skipped):", sum.Duplicate)
+	writeList(&b, "Target already exists on disk (skipped):", sum.TargetExists)
+	writeList(&b, "Collisions resolved:", sum.Resolved)
+	writeList(&b, "Look identical to another name, differing only in Unicode normalisation (renamed anyway):", sum.Normalization)
+
+	return b.String()
+}
+
+func writeList(b *strings.Builder, title string, lines []string) {
+	if len(lines) == 0 {
+		return
+	}
+	b.WriteString(title + "\n")
+	for _, s := range firstN(lines, 20) {
+		b.WriteString(" - " + s + "\n")
+	}
+	if len(lines) > 20 {
+		b.WriteString(fmt.Sprintf(" ... and %d more\n", len(lines)-20))
+	}
+	b.WriteString("\n")
+}
+
+func FormatResult(items []RenamePlanItem, dryRun bool) string {
+	var renamed, skipped, errors int
+	for _, it := range items {
+		switch it.Status {
+		case StatusRenamed:
+			renamed++
+		case StatusSkip:
+			skipped++
+		case StatusError:
+			errors++
+		case StatusDryRun:
+			renamed++
+		}
+	}
+
+	if dryRun {
+		return fmt.Sprintf("Dry run complete.\nWould rename: %d\nSkipped: %d\nErrors: %d", renamed, skipped, errors)
+	}
+	return fmt.Sprintf("Apply complete.\nRenamed: %d\nSkipped: %d\nErrors: %d", renamed, skipped, errors)
+}
+
+/* -------------------- small helpers -------------------- */
+
+func firstN[T any](in []T, n int) []T {
+	if len(in) <= n {
+		return in
+	}
+	return in[:n]
+}
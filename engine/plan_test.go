@@ -0,0 +1,155 @@
+package engine
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeInfo is the FileInfo of a listed file that may not match the disk.
+type fakeInfo struct {
+	name string
+	size int64
+	mod  time.Time
+}
+
+func (fi fakeInfo) Name() string       { return fi.name }
+func (fi fakeInfo) Size() int64        { return fi.size }
+func (fi fakeInfo) Mode() fs.FileMode  { return 0o644 }
+func (fi fakeInfo) ModTime() time.Time { return fi.mod }
+func (fi fakeInfo) IsDir() bool        { return false }
+func (fi fakeInfo) Sys() any           { return nil }
+
+// listing is a scan of dir with the given name → size.
+func listing(dir string, sizes map[string]int64) []File {
+	var files []File
+	for name, size := range sizes {
+		files = append(files, File{Path: filepath.Join(dir, name), Info: fakeInfo{name: name, size: size}})
+	}
+	return files
+}
+
+func TestPlanInvalidNames(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.txt": "A"})
+	src := filepath.Join(dir, "a.txt")
+
+	tests := []struct {
+		name string
+		step RenameStep
+		want string // "" for a valid name
+	}{
+		{"plain", RenameStep{Op: OpPrepend, A: "x-"}, ""},
+		{"separator", RenameStep{Op: OpPrepend, A: "sub/"}, "invalid characters"},
+		{"windows colon", RenameStep{Op: OpPrepend, A: "c:"}, "invalid characters"},
+		{"reserved", RenameStep{Op: OpReplaceText, A: "a", B: "con"}, "reserved filename"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := Options{Steps: []RenameStep{tc.step}, Profile: ProfilePortable}
+			items, sum := Plan([]string{src}, opts)
+			it := items[0]
+			if tc.want == "" {
+				if it.Status != StatusOK {
+					t.Errorf("%s: %s (%s), want ok", it.NewName, it.Status, it.Reason)
+				}
+				return
+			}
+			if it.Status != StatusSkip || it.Reason != "invalid: "+tc.want {
+				t.Errorf("%s: %s (%s), want skip with %q", it.NewName, it.Status, it.Reason, tc.want)
+			}
+			if len(sum.Invalid) != 1 || !strings.Contains(sum.Invalid[0], tc.want) {
+				t.Errorf("summary invalid = %v", sum.Invalid)
+			}
+		})
+	}
+}
+
+func TestPlanUsesListing(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.txt": "A", "b.txt": "B"})
+	a := filepath.Join(dir, "a.txt")
+
+	// the disk has a tie, which the existing b.txt would win; the listing
+	// makes a.txt larger
+	opts := Options{
+		Steps:     []RenameStep{{Op: OpReplaceText, A: "a", B: "b"}},
+		Collision: CollisionKeepLarger,
+		CaseFS:    CaseFSSensitive,
+		Listing:   listing(dir, map[string]int64{"a.txt": 100, "b.txt": 10}),
+	}
+	items, _ := Plan([]string{a}, opts)
+	if items[0].Status != StatusOK || items[0].BackupPath == "" {
+		t.Errorf("keep larger: %s (%s), want a.txt to win with a backup", items[0].Status, items[0].Reason)
+	}
+
+	// "café" spelt decomposed is only in the listing
+	opts = Options{
+		Steps:   []RenameStep{{Op: OpReplaceText, A: "a", B: "caf\u00e9"}},
+		CaseFS:  CaseFSSensitive,
+		Listing: listing(dir, map[string]int64{"a.txt": 1, "cafe\u0301.txt": 1}),
+	}
+	items, sum := Plan([]string{a}, opts)
+	if items[0].Warning == "" || len(sum.Normalization) != 1 {
+		t.Errorf("normalisation: warning %q, summary %v; want café flagged", items[0].Warning, sum.Normalization)
+	}
+}
+
+func TestCheckConflicts(t *testing.T) {
+	type move struct{ from, to string }
+	tests := []struct {
+		name   string
+		caseFS string
+		disk   []string
+		moves  []move
+		want   []string // Reason of each item, "" when it goes ahead
+	}{
+		{"swap", CaseFSSensitive, []string{"a", "b"},
+			[]move{{"a", "b"}, {"b", "a"}}, []string{"", ""}},
+		{"chain", CaseFSSensitive, []string{"a", "b"},
+			[]move{{"a", "b"}, {"b", "c"}}, []string{"", ""}},
+		{"cycle", CaseFSSensitive, []string{"a", "b", "c"},
+			[]move{{"a", "b"}, {"b", "c"}, {"c", "a"}}, []string{"", "", ""}},
+		{"chain onto a file that stays", CaseFSSensitive, []string{"a", "b", "c"},
+			[]move{{"a", "b"}, {"b", "c"}}, []string{ReasonTargetExists, ReasonTargetExists}},
+		{"same target", CaseFSSensitive, []string{"a", "b"},
+			[]move{{"a", "c"}, {"b", "c"}}, []string{ReasonDuplicate, ReasonDuplicate}},
+		{"missing source", CaseFSSensitive, []string{"a"},
+			[]move{{"x", "y"}, {"a", "x"}}, []string{ReasonMissing, ""}},
+		{"case-insensitive target", CaseFSInsensitive, []string{"a", "b"},
+			[]move{{"a", "B"}}, []string{ReasonTargetExists}},
+		{"case-sensitive target", CaseFSSensitive, []string{"a", "b"},
+			[]move{{"a", "B"}}, []string{""}},
+		{"case change", CaseFSInsensitive, []string{"a"},
+			[]move{{"a", "A"}}, []string{""}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, name := range tc.disk {
+				writeFiles(t, dir, map[string]string{name: name})
+			}
+			var items []RenamePlanItem
+			for _, m := range tc.moves {
+				items = append(items, planItem(dir, m.from, m.to))
+			}
+			var sum PlanSummary
+			checkConflicts(items, &sum, CollisionSkip, newPathKeys(tc.caseFS, nil))
+
+			ok := 0
+			for i, it := range items {
+				if it.Reason != tc.want[i] {
+					t.Errorf("%s → %s: reason %q, want %q", it.OldName, it.NewName, it.Reason, tc.want[i])
+				}
+				if it.Status == StatusOK {
+					ok++
+				}
+			}
+			if sum.OkCount != ok {
+				t.Errorf("OkCount = %d, want %d", sum.OkCount, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+/* -------------------- Presets -------------------- */
+
+// PresetVersion is written into every preset. Readers accept older versions
+// and refuse newer ones rather than silently dropping fields.
+//
+//	1  flat filter list plus one match_all switch
+//	2  filters are a tree of groups (FilterGroup)
+//	3  scan options (ScanOptions)
+const PresetVersion = 3
+
+// Preset is a named, shareable set of filters, options and rename steps.
+type Preset struct {
+	Version       int          `json:"version"`
+	Name          string       `json:"name"`
+	Filters       FilterGroup  `json:"filters"`
+	CaseSensitive bool         `json:"case_sensitive"`
+	Recursive     bool         `json:"recursive"`
+	Steps         []RenameStep `json:"steps"`
+
+	NormalizeMatch bool        `json:"normalize_match,omitempty"`
+	Scan           ScanOptions `json:"scan,omitzero"`
+}
+
+// presetV1 reads version 1 presets; its filters field shadows the embedded
+// one.
+type presetV1 struct {
+	Preset
+	Filters  []FilterRule `json:"filters"`
+	MatchAll bool         `json:"match_all"`
+}
+
+// NewPreset captures the filters, options and steps of opts.
+func NewPreset(name string, opts Options) Preset {
+	return Preset{
+		Version:       PresetVersion,
+		Name:          name,
+		Filters:       opts.Filters.Clone(),
+		CaseSensitive: opts.CaseSensitive,
+		Recursive:     opts.Recursive,
+		Steps:         append([]RenameStep(nil), opts.Steps...),
+
+		NormalizeMatch: opts.NormalizeMatch,
+		Scan:           cloneScanOptions(opts.Scan),
+	}
+}
+
+// ApplyTo copies the preset into opts, leaving the folder alone. Filter and
+// step IDs are renumbered from 1.
+func (p Preset) ApplyTo(opts *Options) {
+	opts.Filters = p.Filters.Clone()
+	opts.Filters.Renumber(1)
+	opts.Steps = make([]RenameStep, len(p.Steps))
+	for i, s := range p.Steps {
+		s.ID = i + 1
+		opts.Steps[i] = s
+	}
+	opts.CaseSensitive = p.CaseSensitive
+	opts.NormalizeMatch = p.NormalizeMatch
+	opts.Recursive = p.Recursive
+	opts.Scan = cloneScanOptions(p.Scan)
+}
+
+func cloneScanOptions(so ScanOptions) ScanOptions {
+	so.Exclude = append([]string(nil), so.Exclude...)
+	return so
+}
+
+func (p Preset) validate() error {
+	switch {
+	case p.Version <= 0:
+		return errors.New("not a RenForge preset (missing version)")
+	case p.Version > PresetVersion:
+		return fmt.Errorf("preset %q was saved by a newer RenForge (format %d, this build reads up to %d)", p.Name, p.Version, PresetVersion)
+	case strings.TrimSpace(p.Name) == "":
+		return errors.New("preset has no name")
+	}
+	return nil
+}
+
+// MarshalPreset encodes a single preset for export.
+func MarshalPreset(p Preset) ([]byte, error) {
+	p.Version = PresetVersion
+	return json.MarshalIndent(p, "", "  ")
+}
+
+// UnmarshalPreset decodes and checks an exported preset, upgrading older
+// versions.
+func UnmarshalPreset(data []byte) (Preset, error) {
+	var head struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &head); err != nil {
+		return Preset{}, fmt.Errorf("reading preset: %w", err)
+	}
+
+	var p Preset
+	if head.Version == 1 {
+		var v1 presetV1
+		if err := json.Unmarshal(data, &v1); err != nil {
+			return Preset{}, fmt.Errorf("reading preset: %w", err)
+		}
+		p = v1.Preset
+		p.Filters = FilterGroup{MatchAll: v1.MatchAll, Rules: v1.Filters}
+	} else if err := json.Unmarshal(data, &p); err != nil {
+		return Preset{}, fmt.Errorf("reading preset: %w", err)
+	}
+	if err := p.validate(); err != nil {
+		return Preset{}, err
+	}
+	return p, nil
+}
+
+// LoadPresetFile reads a preset exported with MarshalPreset.
+func LoadPresetFile(path string) (Preset, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Preset{}, err
+	}
+	return UnmarshalPreset(data)
+}
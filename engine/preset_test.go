@@ -0,0 +1,90 @@
+package engine
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUnmarshalPreset(t *testing.T) {
+	steps := []RenameStep{{Op: OpReplaceText, A: "IMG", B: "Photo"}}
+	tests := []struct {
+		name string
+		data string
+		want Preset
+		err  string // part of the error, "" for none
+	}{
+		{"version 1", `{
+			"version": 1, "name": "photos", "case_sensitive": true,
+			"filters": [{"mode": "extension", "value": "jpg"}, {"mode": "contains", "value": "thumb", "not": true}],
+			"match_all": false,
+			"steps": [{"op": "Replace text", "a": "IMG", "b": "Photo"}]
+		}`, Preset{
+			Version: 1, Name: "photos", CaseSensitive: true, Steps: steps,
+			Filters: FilterGroup{Rules: []FilterRule{
+				{Mode: "extension", Value: "jpg"}, {Mode: "contains", Value: "thumb", Not: true},
+			}},
+		}, ""},
+		{"version 1 without filters", `{"version": 1, "name": "all", "match_all": true}`,
+			Preset{Version: 1, Name: "all", Filters: FilterGroup{MatchAll: true}}, ""},
+		{"version 2", `{
+			"version": 2, "name": "nested", "recursive": true,
+			"filters": {"match_all": true, "groups": [{"match_all": false, "not": true, "rules": [{"mode": "glob", "value": "raw/**"}]}]}
+		}`, Preset{
+			Version: 2, Name: "nested", Recursive: true,
+			Filters: FilterGroup{MatchAll: true, Groups: []FilterGroup{
+				{Not: true, Rules: []FilterRule{{Mode: "glob", Value: "raw/**"}}},
+			}},
+		}, ""},
+		{"version 3", `{
+			"version": 3, "name": "scan", "filters": {"match_all": true},
+			"scan": {"max_depth": 2, "exclude": ["node_modules"], "skip_hidden": true}
+		}`, Preset{
+			Version: 3, Name: "scan", Filters: FilterGroup{MatchAll: true},
+			Scan: ScanOptions{MaxDepth: 2, Exclude: []string{"node_modules"}, SkipHidden: true},
+		}, ""},
+		{"newer", `{"version": 99, "name": "future"}`, Preset{}, "newer RenForge"},
+		{"no version", `{"name": "x"}`, Preset{}, "missing version"},
+		{"no name", `{"version": 3, "name": " "}`, Preset{}, "no name"},
+		{"not json", `version 1`, Preset{}, "reading preset"},
+	}
+	for _, tc := range tests {
+		got, err := UnmarshalPreset([]byte(tc.data))
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("%s: error %v, want one mentioning %q", tc.name, err, tc.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s:\n got %+v\nwant %+v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestPresetRoundTrip(t *testing.T) {
+	opts := Options{
+		Filters:   FilterGroup{ID: 1, MatchAll: true, Rules: []FilterRule{{ID: 2, Mode: "size", Value: ">1MB"}}},
+		Recursive: true,
+		Steps:     []RenameStep{{ID: 7, Op: OpChangeCase, Case: CaseOptions{Style: CaseLower}}},
+		Scan:      ScanOptions{Exclude: []string{"build/**"}, UseIgnore: true},
+	}
+	data, err := MarshalPreset(NewPreset("round trip", opts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := UnmarshalPreset(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Options
+	p.ApplyTo(&got)
+	opts.Steps[0].ID = 1 // ApplyTo renumbers
+	if !reflect.DeepEqual(got, opts) {
+		t.Errorf("got %+v\nwant %+v", got, opts)
+	}
+}
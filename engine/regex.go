@@ -0,0 +1,42 @@
+package engine
+
+import (
+	"regexp"
+	"sync"
+)
+
+/* -------------------- Regex cache -------------------- */
+
+// Filters and steps run once per file, so compiled patterns are cached by
+// source text instead of being recompiled for every name. The cache is
+// dropped when it grows past maxCachedRegex (every keystroke in a pattern
+// entry adds one).
+const maxCachedRegex = 256
+
+var (
+	regexMu    sync.Mutex
+	regexCache = map[string]*regexp.Regexp{}
+)
+
+// compileRegex compiles pattern, prefixing (?i) when caseSensitive is false.
+func compileRegex(pattern string, caseSensitive bool) (*regexp.Regexp, error) {
+	if !caseSensitive {
+		pattern = "(?i)" + pattern
+	}
+
+	regexMu.Lock()
+	defer regexMu.Unlock()
+
+	if re, ok := regexCache[pattern]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(regexCache) >= maxCachedRegex {
+		regexCache = map[string]*regexp.Regexp{}
+	}
+	regexCache[pattern] = re
+	return re, nil
+}
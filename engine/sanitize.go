@@ -0,0 +1,247 @@
+package engine
+
+import (
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+/* -------------------- Sanitise -------------------- */
+
+// What OpSanitize does with characters the naming profile doesn't allow.
+const (
+	SanitizeReplace = "Replace invalid with _"
+	SanitizeRemove  = "Remove invalid"
+)
+
+// SanitizeInvalidModes lists the modes in UI order; "" means SanitizeReplace.
+var SanitizeInvalidModes = []string{SanitizeReplace, SanitizeRemove}
+
+// sanitizeFallback names files that have nothing usable left.
+const sanitizeFallback = "untitled"
+
+type SanitizeOptions struct {
+	URLDecode     bool   `json:"url_decode,omitempty"`     // "My%20File.pdf" → "My File.pdf"
+	Invalid       string `json:"invalid,omitempty"`        // one of SanitizeInvalidModes
+	CollapseSpace bool   `json:"collapse_space,omitempty"` // runs of whitespace → one space, none at the ends
+	TrimPunct     bool   `json:"trim_punct,omitempty"`     // "--draft!.txt" → "draft.txt"
+	RemoveEmoji   bool   `json:"remove_emoji,omitempty"`
+	Slug          bool   `json:"slug,omitempty"` // lower case ASCII words joined by dashes
+}
+
+func DefaultSanitize() SanitizeOptions {
+	return SanitizeOptions{URLDecode: true, Invalid: SanitizeReplace, CollapseSpace: true, TrimPunct: true}
+}
+
+// applySanitize cleans name up for a filesystem following profile. Whatever
+// the options, the result passes InvalidNameReason for that profile: bad
+// characters are replaced (or removed), reserved names get a "_", trailing
+// dots and spaces go and long names are cut down keeping the extension.
+func applySanitize(name string, opts SanitizeOptions, profile string) string {
+	r := rulesFor(profile)
+	repl := "_"
+	if opts.Invalid == SanitizeRemove {
+		repl = ""
+	}
+
+	if opts.URLDecode {
+		name = urlDecode(name)
+	}
+	if opts.RemoveEmoji {
+		name = strings.Map(func(c rune) rune {
+			if isEmoji(c) {
+				return -1
+			}
+			return c
+		}, name)
+	}
+	name = replaceInvalid(name, r, repl)
+
+	if opts.Slug {
+		name = slugify(name)
+	} else {
+		if opts.CollapseSpace {
+			base, ext := splitExt(name)
+			name = strings.Join(strings.Fields(base), " ") + ext
+		}
+		if opts.TrimPunct {
+			name = trimPunct(name)
+		}
+	}
+
+	name = fitName(name, r, repl)
+	if InvalidNameReason(name, profile) != "" {
+		return sanitizeFallback
+	}
+	return name
+}
+
+// urlDecode turns %XX escapes back into characters. Stray "%" signs are
+// left alone, and so is the whole name if decoding doesn't give valid UTF-8.
+func urlDecode(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+	b := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]) {
+			b = append(b, unhex(s[i+1])<<4|unhex(s[i+2]))
+			i += 2
+			continue
+		}
+		b = append(b, s[i])
+	}
+	if !utf8.Valid(b) {
+		return s
+	}
+	return string(b)
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case c <= '9':
+		return c - '0'
+	case c <= 'F':
+		return c - 'A' + 10
+	default:
+		return c - 'a' + 10
+	}
+}
+
+// isEmoji covers the pictographic blocks plus the joiners, variation
+// selectors and tags emoji sequences are built from.
+func isEmoji(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF, // mahjong … symbols and pictographs extended-A
+		r >= 0x2600 && r <= 0x27BF,   // misc symbols, dingbats
+		r >= 0x2300 && r <= 0x23FF,   // ⌚ ⏰ ⏳
+		r >= 0x2B00 && r <= 0x2BFF,   // ⬆ ⭐
+		r >= 0xFE00 && r <= 0xFE0F,   // variation selectors
+		r >= 0xE0020 && r <= 0xE007F, // tags (subdivision flags)
+		r == 0x200D, r == 0x20E3:     // zero width joiner, keycap
+		return true
+	}
+	return false
+}
+
+// replaceInvalid swaps the characters r doesn't allow (NUL always) for repl.
+func replaceInvalid(s string, r nameRules, repl string) string {
+	var b strings.Builder
+	for _, c := range s {
+		if c == 0 || strings.ContainsRune(r.invalid, c) || (r.control && c < 0x20) {
+			b.WriteString(repl)
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// splitExt is the base/extension split of applyCase: dot-files like ".env"
+// are all base.
+func splitExt(name string) (base, ext string) {
+	ext = filepath.Ext(name)
+	base = strings.TrimSuffix(name, ext)
+	if base == "" {
+		base, ext = ext, ""
+	}
+	return base, ext
+}
+
+// trimPunct strips punctuation and spaces from both ends of the base name.
+// Brackets and quotes that open at the start or close at the end stay, as
+// does the dot of a dot-file.
+func trimPunct(name string) string {
+	base, ext := splitExt(name)
+	dot := ""
+	if strings.HasPrefix(base, ".") {
+		dot, base = ".", base[1:]
+	}
+	trimmed := strings.TrimLeftFunc(base, func(r rune) bool {
+		return unicode.IsSpace(r) || (unicode.IsPunct(r) && !unicode.In(r, unicode.Ps, unicode.Pi))
+	})
+	trimmed = strings.TrimRightFunc(trimmed, func(r rune) bool {
+		return unicode.IsSpace(r) || (unicode.IsPunct(r) && !unicode.In(r, unicode.Pe, unicode.Pf))
+	})
+	if trimmed == "" {
+		return name
+	}
+	return dot + trimmed + ext
+}
+
+// slugify spells the name as lower case ASCII words joined by dashes:
+// "Été 2024 — Photos (1).JPG" → "ete-2024-photos-1.jpg".
+func slugify(name string) string {
+	base, ext := splitExt(transliterate(name))
+	dot := ""
+	if strings.HasPrefix(base, ".") {
+		dot = "."
+	}
+	base = slugWords(base, "-")
+	if base == "" {
+		base = sanitizeFallback
+	}
+	if ext = slugWords(ext, ""); ext != "" {
+		ext = "." + ext
+	}
+	return dot + base + ext
+}
+
+// slugWords lower-cases s and joins its runs of ASCII letters and digits
+// with sep.
+func slugWords(s, sep string) string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !('a' <= r && r <= 'z' || '0' <= r && r <= '9')
+	})
+	return strings.Join(words, sep)
+}
+
+// fitName makes name acceptable to r after the cosmetic passes: it is what
+// lets applySanitize promise a valid name. Outer spaces go first, as the
+// pipeline trims them after the last step and " .." would become "..".
+func fitName(name string, r nameRules, repl string) string {
+	name = strings.TrimSpace(replaceInvalid(name, r, repl))
+	if r.trailingDot {
+		name = strings.TrimRight(name, ". ")
+	}
+	if name == "" || name == "." || name == ".." {
+		name = sanitizeFallback
+	}
+
+	if r.reserved {
+		// "con.txt" → "con_.txt", as InvalidNameReason looks before the first dot
+		base, rest, _ := strings.Cut(name, ".")
+		if reservedNames[strings.ToUpper(strings.TrimSpace(base))] {
+			name = strings.TrimSpace(base) + "_"
+			if rest != "" {
+				name += "." + rest
+			}
+		}
+	}
+
+	tooLong := func(s string) bool {
+		return (r.maxBytes > 0 && len(s) > r.maxBytes) ||
+			(r.maxUnits > 0 && len(utf16.Encode([]rune(s))) > r.maxUnits)
+	}
+	for tooLong(name) {
+		base, ext := splitExt(name)
+		if len(ext) > 16 {
+			base, ext = name, "" // not really an extension
+		}
+		_, size := utf8.DecodeLastRuneInString(base)
+		name = base[:len(base)-size] + ext
+	}
+	if r.trailingDot {
+		name = strings.TrimRight(name, ". ")
+	}
+	if name = strings.TrimSpace(name); name == "" || name == "." || name == ".." {
+		name = sanitizeFallback
+	}
+	return name
+}
@@ -0,0 +1,32 @@
+package engine
+
+import "testing"
+
+func TestSanitizeSteps(t *testing.T) {
+	tests := []struct {
+		in, want string // want is for Linux; every profile must get a valid name
+		opts     SanitizeOptions
+	}{
+		{" ..", sanitizeFallback, DefaultSanitize()},
+		{" .. ", sanitizeFallback, SanitizeOptions{}},
+		{" . ", sanitizeFallback, SanitizeOptions{}},
+		{"   ", sanitizeFallback, SanitizeOptions{}},
+		{" a.txt ", "a.txt", SanitizeOptions{}},
+		{"a/b.txt", "a_b.txt", SanitizeOptions{}},
+		{"a%2Fb.txt", "a_b.txt", DefaultSanitize()},
+		{"..%2F..", ".._..", SanitizeOptions{URLDecode: true}},
+		{"Été 2024 (1).JPG", "ete-2024-1.jpg", SanitizeOptions{Slug: true}},
+	}
+	for _, tc := range tests {
+		steps := []RenameStep{{Op: OpSanitize, Sanitize: tc.opts}}
+		for _, profile := range Profiles {
+			got := applySteps(tc.in, steps, stepContext{profile: profile}, nil)
+			if reason := InvalidNameReason(got, profile); reason != "" {
+				t.Errorf("%s: %q → %q: %s", profile, tc.in, got, reason)
+			}
+			if profile == ProfileLinux && got != tc.want {
+				t.Errorf("%q → %q, want %q", tc.in, got, tc.want)
+			}
+		}
+	}
+}
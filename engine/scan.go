@@ -0,0 +1,265 @@
+package engine
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+/* -------------------- File listing -------------------- */
+
+// File is a listed file with the info read while listing it, so filters on
+// size, dates or attributes don't stat every file again.
+type File struct {
+	Path string
+	// Info describes the link target for symlinks (the link itself when it
+	// is broken); nil when the file vanished while listing
+	Info    fs.FileInfo
+	Symlink bool
+	// Created is the birth time where the filesystem records it, otherwise
+	// the modification time; zero when Info is nil
+	Created time.Time
+}
+
+// Paths returns the paths of files, in order.
+func Paths(files []File) []string {
+	out := make([]string, len(files))
+	for i, f := range files {
+		out[i] = f.Path
+	}
+	return out
+}
+
+// ScanOptions narrow down what a scan reaches. The zero value lists
+// everything, which is what ListAllFiles does.
+type ScanOptions struct {
+	MaxDepth    int      `json:"max_depth,omitempty"`    // subfolder levels to descend when recursive; 0 is no limit
+	Exclude     []string `json:"exclude,omitempty"`      // folders to skip, as globs: "node_modules", "build/**"
+	SkipHidden  bool     `json:"skip_hidden,omitempty"`  // leave out dot-files and dot-folders
+	UseIgnore   bool     `json:"use_ignore,omitempty"`   // honour .gitignore and .ignore files
+	FollowLinks bool     `json:"follow_links,omitempty"` // descend into symlinked folders, once each
+}
+
+// Scan lists the files in opts.Folder, descending into subfolders when
+// opts.Recursive is set, as narrowed by opts.Scan. Files are returned sorted
+// by path. Subfolders that can't be read are skipped; ScanContext says which.
+func Scan(opts Options) ([]File, error) {
+	files, _, err := ScanContext(context.Background(), opts, nil)
+	return files, err
+}
+
+func ListAllFiles(folder string, recursive bool) ([]File, error) {
+	files, _, err := listFiles(context.Background(), folder, recursive, ScanOptions{}, nil)
+	return files, err
+}
+
+// ScanProgress is what a running scan reports: the totals so far and the
+// files found since the previous report, in the order they were found.
+type ScanProgress struct {
+	Files int
+	Dirs  int
+	New   []File
+}
+
+// scanReportInterval spaces out progress reports, so a consumer redrawing on
+// each one keeps up with a fast disk.
+const scanReportInterval = 250 * time.Millisecond
+
+// ScanContext is Scan for big or slow folders: report (when not nil) is
+// called from the scanning goroutine every so often and once at the end,
+// and the scan stops early when ctx is done, returning the files found so
+// far with ctx's error. Subfolders that can't be read (e.g. permission
+// denied) don't stop the scan; their errors come back in skipped.
+func ScanContext(ctx context.Context, opts Options, report func(ScanProgress)) (files []File, skipped []error, err error) {
+	return listFiles(ctx, opts.Folder, opts.Recursive, opts.Scan, report)
+}
+
+func listFiles(ctx context.Context, folder string, recursive bool, so ScanOptions, report func(ScanProgress)) ([]File, []error, error) {
+	root, err := os.Stat(folder)
+	if err != nil {
+		return nil, nil, err
+	}
+	w := walker{
+		ctx:        ctx,
+		root:       folder,
+		recursive:  recursive,
+		opts:       so,
+		visited:    map[fileID]bool{},
+		report:     report,
+		lastReport: time.Now(),
+	}
+	w.visit(root)
+	err = w.walk(folder, 0, nil)
+	w.flush()
+	sort.Slice(w.files, func(i, j int) bool { return w.files[i].Path < w.files[j].Path })
+	return w.files, w.skipped, err
+}
+
+type walker struct {
+	ctx       context.Context
+	root      string
+	recursive bool
+	opts      ScanOptions
+	files     []File
+	skipped   []error
+	// visited holds every folder entered, so a symlinked folder that was
+	// already listed (or contains itself) isn't walked again; once a link
+	// was followed, plain folders are checked too. Folders are keyed by
+	// device and inode; where the platform has neither they are kept in
+	// unkeyed and compared one by one.
+	visited  map[fileID]bool
+	unkeyed  []os.FileInfo
+	dirs     int
+	followed bool
+
+	report     func(ScanProgress)
+	lastReport time.Time
+	reported   int // files already sent to report
+}
+
+// flush reports the files found since the last report.
+func (w *walker) flush() {
+	if w.report == nil {
+		return
+	}
+	w.report(ScanProgress{
+		Files: len(w.files),
+		Dirs:  w.dirs,
+		New:   slices.Clone(w.files[w.reported:]),
+	})
+	w.reported = len(w.files)
+	w.lastReport = time.Now()
+}
+
+// walk lists dir, depth levels below the root, with the ignore rules of the
+// folders above it. Only a cancelled ctx stops it; unreadable subfolders
+// are recorded and skipped.
+func (w *walker) walk(dir string, depth int, ignores []ignoreRule) error {
+	if err := w.ctx.Err(); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if depth == 0 {
+			return err // the chosen folder itself
+		}
+		w.skipped = append(w.skipped, err)
+		if len(entries) == 0 {
+			return nil
+		}
+	}
+	if w.opts.UseIgnore {
+		ignores = append(ignores[:len(ignores):len(ignores)], readIgnoreFiles(dir, RelPath(w.root, dir))...)
+	}
+
+	for _, e := range entries {
+		name := e.Name()
+		if strings.TrimSpace(name) == "" || IsInternalName(name) {
+			continue
+		}
+		if w.opts.SkipHidden && strings.HasPrefix(name, ".") {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		rel := RelPath(w.root, path)
+
+		isDir := e.IsDir()
+		var dirInfo os.FileInfo
+		if e.Type()&fs.ModeSymlink != 0 {
+			fi, err := os.Stat(path)
+			if err == nil && fi.IsDir() {
+				if !w.opts.FollowLinks {
+					continue
+				}
+				isDir, dirInfo = true, fi
+			}
+		}
+
+		if ignored(ignores, rel, isDir) {
+			continue
+		}
+		if !isDir {
+			w.files = append(w.files, newFile(path, e))
+			if w.report != nil && time.Since(w.lastReport) >= scanReportInterval {
+				w.flush()
+			}
+			continue
+		}
+
+		if !w.recursive || (w.opts.MaxDepth > 0 && depth >= w.opts.MaxDepth) || w.excluded(rel) {
+			continue
+		}
+		linked := dirInfo != nil
+		if !linked {
+			if dirInfo, err = e.Info(); err != nil {
+				w.skipped = append(w.skipped, err)
+				continue
+			}
+		}
+		if (linked || w.followed) && w.seen(dirInfo) {
+			continue
+		}
+		w.followed = w.followed || linked
+		w.visit(dirInfo)
+		if err := w.walk(path, depth+1, ignores); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *walker) excluded(rel string) bool {
+	for _, pattern := range w.opts.Exclude {
+		if pattern = strings.TrimSpace(pattern); pattern != "" && matchGlob(pattern, rel, false) {
+			return true
+		}
+	}
+	return false
+}
+
+// fileID identifies a file by device and inode.
+type fileID struct{ dev, ino uint64 }
+
+func (w *walker) visit(fi os.FileInfo) {
+	w.dirs++
+	if id, ok := fileIDOf(fi); ok {
+		w.visited[id] = true
+	} else {
+		w.unkeyed = append(w.unkeyed, fi)
+	}
+}
+
+func (w *walker) seen(fi os.FileInfo) bool {
+	if id, ok := fileIDOf(fi); ok {
+		return w.visited[id]
+	}
+	for _, v := range w.unkeyed {
+		if os.SameFile(v, fi) {
+			return true
+		}
+	}
+	return false
+}
+
+// newFile reads the info of a listed entry once: the entry's own for plain
+// files, the target's for symlinks. The birth time is read along with it.
+func newFile(path string, d fs.DirEntry) File {
+	f := File{Path: path, Symlink: d.Type()&fs.ModeSymlink != 0}
+	if f.Symlink {
+		if fi, err := os.Stat(path); err == nil {
+			f.Info = fi
+		}
+	}
+	if f.Info == nil {
+		f.Info, _ = d.Info()
+	}
+	if f.Info != nil {
+		f.Created = createdTime(path, f.Info)
+	}
+	return f
+}
@@ -0,0 +1,173 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+// scanned lists what Scan finds in dir, as slash-separated paths relative
+// to it.
+func scanned(t *testing.T, dir string, recursive bool, so ScanOptions) []string {
+	t.Helper()
+	files, err := Scan(Options{Folder: dir, Recursive: recursive, Scan: so})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []string
+	for _, f := range files {
+		out = append(out, RelPath(dir, f.Path))
+	}
+	return out
+}
+
+func TestScanOptions(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.txt":                                    "",
+		".env":                                     "",
+		"debug.log":                                "",
+		".gitignore":                               "*.log\n!keep.log\nbuild/\n",
+		filepath.Join("sub", "b.txt"):              "",
+		filepath.Join("sub", "keep.log"):           "",
+		filepath.Join("sub", ".ignore"):            "/b.txt\n",
+		filepath.Join("sub", "deep", "c.txt"):      "",
+		filepath.Join(".cache", "d.txt"):           "",
+		filepath.Join("build", "out.bin"):          "",
+		filepath.Join("node_modules", "m.js"):      "",
+		filepath.Join("node_modules", "x", "n.js"): "",
+	})
+	all := []string{
+		".cache/d.txt", ".env", ".gitignore", "a.txt", "build/out.bin", "debug.log",
+		"node_modules/m.js", "node_modules/x/n.js", "sub/.ignore", "sub/b.txt", "sub/deep/c.txt", "sub/keep.log",
+	}
+	without := func(drop ...string) []string {
+		return slices.DeleteFunc(slices.Clone(all), func(p string) bool { return slices.Contains(drop, p) })
+	}
+
+	tests := []struct {
+		name      string
+		recursive bool
+		so        ScanOptions
+		want      []string
+	}{
+		{"everything", true, ScanOptions{}, all},
+		{"not recursive", false, ScanOptions{}, []string{".env", ".gitignore", "a.txt", "debug.log"}},
+		{"one level", true, ScanOptions{MaxDepth: 1}, without("node_modules/x/n.js", "sub/deep/c.txt")},
+		{"exclude by name", true, ScanOptions{Exclude: []string{"node_modules", " "}}, without("node_modules/m.js", "node_modules/x/n.js")},
+		{"exclude by glob", true, ScanOptions{Exclude: []string{"node_modules/**", "sub/deep"}}, without("node_modules/m.js", "node_modules/x/n.js", "sub/deep/c.txt")},
+		{"skip hidden", true, ScanOptions{SkipHidden: true}, without(".cache/d.txt", ".env", ".gitignore", "sub/.ignore")},
+		{"ignore files", true, ScanOptions{UseIgnore: true}, without("build/out.bin", "debug.log", "sub/b.txt")},
+	}
+	for _, tc := range tests {
+		if got := scanned(t, dir, tc.recursive, tc.so); !slices.Equal(got, tc.want) {
+			t.Errorf("%s: %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestScanSymlinkLoop(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{filepath.Join("sub", "a.txt"): ""})
+	// sub/up leads back to the root, and twice into sub
+	if err := os.Symlink(dir, filepath.Join(dir, "sub", "up")); err != nil {
+		t.Skipf("can't create symlinks: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(dir, "sub"), filepath.Join(dir, "again")); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := scanned(t, dir, true, ScanOptions{}), []string{"sub/a.txt"}; !slices.Equal(got, want) {
+		t.Errorf("not following links: %v, want %v", got, want)
+	}
+	// every folder is listed once, by the first path that reaches it:
+	// "again" comes before "sub"
+	if got, want := scanned(t, dir, true, ScanOptions{FollowLinks: true}), []string{"again/a.txt"}; !slices.Equal(got, want) {
+		t.Errorf("following links: %v, want %v", got, want)
+	}
+}
+
+// cancelAfter is a context that is cancelled once Err has been asked n
+// times, i.e. when the scan is about to enter its n+1th folder.
+type cancelAfter struct {
+	context.Context
+	n int
+}
+
+func (c *cancelAfter) Err() error {
+	if c.n--; c.n < 0 {
+		return context.Canceled
+	}
+	return nil
+}
+
+func TestScanContextCancel(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.txt":                      "",
+		filepath.Join("s1", "b.txt"): "",
+		filepath.Join("s2", "c.txt"): "",
+	})
+
+	var last ScanProgress
+	files, _, err := ScanContext(&cancelAfter{context.Background(), 2}, Options{Folder: dir, Recursive: true}, func(p ScanProgress) { last = p })
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	var got []string
+	for _, f := range files {
+		got = append(got, RelPath(dir, f.Path))
+	}
+	if want := []string{"a.txt", "s1/b.txt"}; !slices.Equal(got, want) {
+		t.Errorf("files = %v, want the ones found before s2: %v", got, want)
+	}
+	if last.Files != 2 || len(last.New) != 2 {
+		t.Errorf("last report has %d files (%d new), want both", last.Files, len(last.New))
+	}
+
+	files, _, err = ScanContext(&cancelAfter{context.Background(), 0}, Options{Folder: dir, Recursive: true}, nil)
+	if !errors.Is(err, context.Canceled) || len(files) != 0 {
+		t.Errorf("cancelled before starting: %v, %v", files, err)
+	}
+}
+
+func TestScanContextSkipsUnreadable(t *testing.T) {
+	if runtime.GOOS == "windows" || os.Geteuid() == 0 {
+		t.Skip("folder permissions are not enforced")
+	}
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.txt":                          "",
+		filepath.Join("locked", "b.txt"): "",
+		filepath.Join("open", "c.txt"):   "",
+	})
+	locked := filepath.Join(dir, "locked")
+	if err := os.Chmod(locked, 0); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(locked, 0o755) })
+
+	files, skipped, err := ScanContext(context.Background(), Options{Folder: dir, Recursive: true}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Errorf("files %v, want a.txt and open/c.txt", Paths(files))
+	}
+	if len(skipped) != 1 || !errors.Is(skipped[0], fs.ErrPermission) {
+		t.Errorf("skipped %v, want the locked folder", skipped)
+	}
+
+	// the chosen folder itself is an error, not a skip
+	if _, _, err := ScanContext(context.Background(), Options{Folder: locked, Recursive: true}, nil); err == nil {
+		t.Error("unreadable root: no error")
+	}
+	if _, _, err := ScanContext(context.Background(), Options{Folder: filepath.Join(dir, "missing")}, nil); err == nil {
+		t.Error("missing root: no error")
+	}
+}
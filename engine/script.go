@@ -0,0 +1,276 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+/* -------------------- Script export -------------------- */
+
+const (
+	ScriptSh         = "POSIX sh"
+	ScriptPowerShell = "PowerShell"
+	ScriptBatch      = "Windows batch"
+)
+
+// ScriptKinds lists the supported script formats in UI order.
+var ScriptKinds = []string{ScriptSh, ScriptPowerShell, ScriptBatch}
+
+// ScriptExt is the file extension (with dot) for each script kind.
+var ScriptExt = map[string]string{
+	ScriptSh:         ".sh",
+	ScriptPowerShell: ".ps1",
+	ScriptBatch:      ".bat",
+}
+
+// ExportScript turns a plan into a standalone script that performs the same
+// two-phase rename as Apply: every "ok" item is first moved to the same
+// .renforge_tmp_ name Apply would use, then to its final name, so swaps
+// still work. The script checks that sources exist up front and refuses to
+// overwrite a target. Other items are written as comments with their reason.
+func ExportScript(plan []RenamePlanItem, kind string) (string, error) {
+	var w scriptWriter
+	switch kind {
+	case ScriptSh:
+		w = shWriter{}
+	case ScriptPowerShell:
+		w = psWriter{}
+	case ScriptBatch:
+		w = batWriter{}
+	default:
+		return "", fmt.Errorf("unknown script kind %q", kind)
+	}
+
+	ts := time.Now().UnixNano()
+	type move struct{ src, tmp, dst string }
+	var moves []move
+	var skipped []string
+	for i, it := range plan {
+		if it.Status == StatusOK || it.Status == StatusDryRun {
+			if it.BackupPath != "" {
+				// the file being overwritten moves to its backup name first
+				moves = append(moves, move{it.NewPath, tmpPathFor(it.NewPath, ts, len(plan)+i), it.BackupPath})
+			}
+			moves = append(moves, move{it.OldPath, tmpPathFor(it.OldPath, ts, i), it.NewPath})
+			continue
+		}
+		reason := it.Reason
+		if reason == "" {
+			reason = it.Status
+		}
+		skipped = append(skipped, fmt.Sprintf("%s: %s → %s (%s)", it.Status, it.OldPath, it.NewName, reason))
+	}
+
+	if kind == ScriptBatch {
+		// no quoting can carry these through cmd.exe; they can't occur in
+		// Windows names anyway, so the plan was made for another system
+		for _, m := range moves {
+			for _, p := range []string{m.src, m.dst} {
+				if strings.ContainsFunc(p, func(r rune) bool { return r == '"' || unicode.IsControl(r) }) {
+					return "", fmt.Errorf("%q can't be written in a batch file (double quote or control character)", p)
+				}
+			}
+		}
+	}
+
+	var b strings.Builder
+	w.header(&b, len(moves))
+	for _, s := range skipped {
+		w.comment(&b, s)
+	}
+	w.section(&b, "Check every source is still there")
+	for _, m := range moves {
+		w.requireExists(&b, m.src)
+	}
+	w.section(&b, "Phase 1: move every file to a temporary name")
+	for _, m := range moves {
+		w.move(&b, m.src, m.tmp)
+	}
+	w.section(&b, "Phase 2: move each temporary name to its final name")
+	for _, m := range moves {
+		w.requireMissing(&b, m.dst)
+		w.move(&b, m.tmp, m.dst)
+	}
+	w.footer(&b)
+	return b.String(), nil
+}
+
+type scriptWriter interface {
+	header(b *strings.Builder, n int)
+	comment(b *strings.Builder, text string)
+	section(b *strings.Builder, title string)
+	requireExists(b *strings.Builder, path string)
+	requireMissing(b *strings.Builder, path string)
+	move(b *strings.Builder, from, to string)
+	footer(b *strings.Builder)
+}
+
+func headerLines(n int) []string {
+	return []string{
+		"RenForge rename plan, generated " + time.Now().Format("2006-01-02 15:04:05"),
+		fmt.Sprintf("Renames %d file(s) in two phases so swaps (a → b, b → a) are safe.", n),
+	}
+}
+
+// commentSafe keeps comments on one line: control characters (a newline in
+// a file name would end the comment) are written as \xNN.
+func commentSafe(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if unicode.IsControl(r) {
+			fmt.Fprintf(&b, `\x%02x`, r)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+/* ---- POSIX sh ---- */
+
+type shWriter struct{}
+
+// shQuote wraps s in single quotes; nothing inside them is special except
+// the quote itself, written as '\”.
+func shQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func (shWriter) header(b *strings.Builder, n int) {
+	b.WriteString("#!/bin/sh\n")
+	for _, l := range headerLines(n) {
+		b.WriteString("# " + l + "\n")
+	}
+	b.WriteString("set -eu\n\n")
+	b.WriteString("fail() { printf 'renforge: %s %s\\n' \"$1\" \"$2\" >&2; exit 1; }\n\n")
+}
+
+func (shWriter) comment(b *strings.Builder, text string) {
+	b.WriteString("# " + commentSafe(text) + "\n")
+}
+
+func (shWriter) section(b *strings.Builder, title string) {
+	b.WriteString("\n# " + title + "\n")
+}
+
+func (shWriter) requireExists(b *strings.Builder, path string) {
+	q := shQuote(path)
+	fmt.Fprintf(b, "[ -e %s ] || [ -L %s ] || fail 'missing source:' %s\n", q, q, q)
+}
+
+func (shWriter) requireMissing(b *strings.Builder, path string) {
+	q := shQuote(path)
+	fmt.Fprintf(b, "if [ -e %s ] || [ -L %s ]; then fail 'target exists:' %s; fi\n", q, q, q)
+}
+
+func (shWriter) move(b *strings.Builder, from, to string) {
+	fmt.Fprintf(b, "mv -- %s %s\n", shQuote(from), shQuote(to))
+}
+
+func (shWriter) footer(b *strings.Builder) {}
+
+/* ---- PowerShell ---- */
+
+type psWriter struct{}
+
+// psQuote builds a single-quoted (verbatim) PowerShell string. PowerShell
+// treats the typographic quotes ‘ ’ ‚ ‛ as single quotes too, so each of
+// them is doubled like '.
+func psQuote(s string) string {
+	var b strings.Builder
+	b.WriteByte('\'')
+	for _, r := range s {
+		switch r {
+		case '\'', '\u2018', '\u2019', '\u201a', '\u201b':
+			b.WriteRune(r)
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('\'')
+	return b.String()
+}
+
+func (psWriter) header(b *strings.Builder, n int) {
+	// Windows PowerShell 5.1 reads BOM-less scripts in the ANSI code page
+	b.WriteString("\ufeff")
+	for _, l := range headerLines(n) {
+		b.WriteString("# " + l + "\n")
+	}
+	b.WriteString("$ErrorActionPreference = 'Stop'\n")
+}
+
+func (psWriter) comment(b *strings.Builder, text string) {
+	b.WriteString("# " + commentSafe(text) + "\n")
+}
+
+func (psWriter) section(b *strings.Builder, title string) {
+	b.WriteString("\n# " + title + "\n")
+}
+
+func (psWriter) requireExists(b *strings.Builder, path string) {
+	q := psQuote(path)
+	fmt.Fprintf(b, "if (-not (Test-Path -LiteralPath %s)) { throw ('renforge: missing source: ' + %s) }\n", q, q)
+}
+
+func (psWriter) requireMissing(b *strings.Builder, path string) {
+	q := psQuote(path)
+	fmt.Fprintf(b, "if (Test-Path -LiteralPath %s) { throw ('renforge: target exists: ' + %s) }\n", q, q)
+}
+
+// [IO.File]::Move takes literal paths (Move-Item -Destination expands
+// wildcards like [ and ]) and never overwrites.
+func (psWriter) move(b *strings.Builder, from, to string) {
+	fmt.Fprintf(b, "[System.IO.File]::Move(%s, %s)\n", psQuote(from), psQuote(to))
+}
+
+func (psWriter) footer(b *strings.Builder) {}
+
+/* ---- Windows batch ---- */
+
+type batWriter struct{}
+
+// batQuote wraps s in double quotes, which keep & | < > ^ ( ) literal. Only
+// % still expands, so it is doubled; " can't occur in Windows file names.
+// Delayed expansion is disabled in the header so ! stays literal.
+func batQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, "%", "%%") + `"`
+}
+
+func (batWriter) header(b *strings.Builder, n int) {
+	b.WriteString("@echo off\r\n")
+	b.WriteString("setlocal DisableDelayedExpansion\r\n")
+	b.WriteString("chcp 65001 >nul\r\n")
+	for _, l := range headerLines(n) {
+		b.WriteString("REM " + strings.ReplaceAll(l, "%", "%%") + "\r\n")
+	}
+}
+
+func (batWriter) comment(b *strings.Builder, text string) {
+	b.WriteString("REM " + strings.ReplaceAll(commentSafe(text), "%", "%%") + "\r\n")
+}
+
+func (batWriter) section(b *strings.Builder, title string) {
+	b.WriteString("\r\nREM " + title + "\r\n")
+}
+
+func (batWriter) requireExists(b *strings.Builder, path string) {
+	q := batQuote(path)
+	fmt.Fprintf(b, "if not exist %s (\r\n  echo renforge: missing source: %s 1>&2\r\n  goto :abort\r\n)\r\n", q, q)
+}
+
+// move overwrites silently inside batch files, so every target is checked
+// first.
+func (batWriter) requireMissing(b *strings.Builder, path string) {
+	q := batQuote(path)
+	fmt.Fprintf(b, "if exist %s (\r\n  echo renforge: target exists: %s 1>&2\r\n  goto :abort\r\n)\r\n", q, q)
+}
+
+func (batWriter) move(b *strings.Builder, from, to string) {
+	fmt.Fprintf(b, "move %s %s >nul || goto :abort\r\n", batQuote(from), batQuote(to))
+}
+
+func (batWriter) footer(b *strings.Builder) {
+	b.WriteString("\r\nexit /b 0\r\n\r\n:abort\r\necho renforge: stopped, see the message above 1>&2\r\nexit /b 1\r\n")
+}
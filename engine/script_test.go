@@ -0,0 +1,99 @@
+package engine
+
+import (
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScriptQuoting(t *testing.T) {
+	tests := []struct {
+		in, sh, ps, bat string
+	}{
+		{"plain.txt", `'plain.txt'`, `'plain.txt'`, `"plain.txt"`},
+		{"it's.txt", `'it'\''s.txt'`, `'it''s.txt'`, `"it's.txt"`},
+		{"it’s.txt", `'it’s.txt'`, `'it’’s.txt'`, `"it’s.txt"`},
+		{"$HOME.txt", `'$HOME.txt'`, `'$HOME.txt'`, `"$HOME.txt"`},
+		{"`date`.txt", "'`date`.txt'", "'`date`.txt'", "\"`date`.txt\""},
+		{"100%.txt", `'100%.txt'`, `'100%.txt'`, `"100%%.txt"`},
+		{"%PATH%.txt", `'%PATH%.txt'`, `'%PATH%.txt'`, `"%%PATH%%.txt"`},
+		{"wow!.txt", `'wow!.txt'`, `'wow!.txt'`, `"wow!.txt"`},
+		{"a & b (1).txt", `'a & b (1).txt'`, `'a & b (1).txt'`, `"a & b (1).txt"`},
+		{"two\nlines", "'two\nlines'", "'two\nlines'", "\"two\nlines\""},
+	}
+	for _, tc := range tests {
+		if got := shQuote(tc.in); got != tc.sh {
+			t.Errorf("shQuote(%q) = %s, want %s", tc.in, got, tc.sh)
+		}
+		if got := psQuote(tc.in); got != tc.ps {
+			t.Errorf("psQuote(%q) = %s, want %s", tc.in, got, tc.ps)
+		}
+		if got := batQuote(tc.in); got != tc.bat {
+			t.Errorf("batQuote(%q) = %s, want %s", tc.in, got, tc.bat)
+		}
+	}
+}
+
+func TestExportScriptComments(t *testing.T) {
+	skipped := RenamePlanItem{OldPath: "/d/two\nlines %x%", NewName: "b", Status: StatusSkip, Reason: "test"}
+	for _, kind := range ScriptKinds {
+		script, err := ExportScript([]RenamePlanItem{skipped}, kind)
+		if err != nil {
+			t.Fatalf("%s: %v", kind, err)
+		}
+		if !strings.Contains(script, `two\x0alines`) {
+			t.Errorf("%s: the newline in a skipped name isn't escaped:\n%s", kind, script)
+		}
+		if kind == ScriptBatch && !strings.Contains(script, "%%x%%") {
+			t.Errorf("%s: %% in a comment isn't doubled:\n%s", kind, script)
+		}
+	}
+
+	ok := RenamePlanItem{OldPath: "/d/a", NewPath: "/d/two\nlines", Status: StatusOK}
+	if _, err := ExportScript([]RenamePlanItem{ok}, ScriptBatch); err == nil {
+		t.Error("batch script with a newline in a name: no error")
+	}
+}
+
+// TestExportScriptSh runs the sh script on names its quoting has to carry.
+func TestExportScriptSh(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no sh")
+	}
+	dir := t.TempDir()
+	names := []string{"it's", "$HOME", "`touch pwned`", "100%!", "two\nlines", "-n"}
+	files := map[string]string{}
+	for _, name := range names {
+		files[name] = name
+	}
+	writeFiles(t, dir, files)
+
+	// each file takes the next one's name, round the circle
+	var plan []RenamePlanItem
+	want := map[string]string{}
+	for i, name := range names {
+		next := names[(i+1)%len(names)]
+		plan = append(plan, planItem(dir, name, next))
+		want[next] = name
+	}
+	script, err := ExportScript(plan, ScriptSh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "rename.sh")
+	if err := os.WriteFile(path, []byte(script), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(sh, path)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("%v: %s\n%s", err, out, script)
+	}
+	if got := readDir(t, dir); !maps.Equal(got, want) {
+		t.Errorf("folder = %q, want %q", got, want)
+	}
+}
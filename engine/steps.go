@@ -0,0 +1,134 @@
+package engine
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+/* -------------------- Rename pipeline -------------------- */
+
+// ApplyRenameSteps runs steps over a single name. Steps that need the rest
+// of the selection or the file on disk (numbering, most template tokens)
+// have nothing to work with; use a Batch for those.
+func ApplyRenameSteps(original string, steps []RenameStep) string {
+	return applySteps(original, steps, stepContext{}, nil)
+}
+
+// applySteps runs steps over original. changed, when not nil, has room for
+// every step and records which of them changed the name.
+func applySteps(original string, steps []RenameStep, ctx stepContext, changed []bool) string {
+	if len(steps) == 0 {
+		return original
+	}
+	name := original
+	for i, s := range steps {
+		next := applyStep(name, i, s, ctx)
+		if changed != nil {
+			changed[i] = next != name
+		}
+		name = next
+	}
+
+	return strings.TrimSpace(name)
+}
+
+// applyStep runs s, step i of the pipeline, over name; i picks the step's
+// sequence numbers out of ctx.
+func applyStep(name string, i int, s RenameStep, ctx stepContext) string {
+	switch s.Op {
+	case OpRemoveText:
+		if s.A != "" {
+			name = strings.ReplaceAll(name, s.A, "")
+		}
+	case OpReplaceText:
+		if s.A != "" {
+			name = strings.ReplaceAll(name, s.A, s.B)
+		}
+	case OpInsertBeforeExt, OpAppend:
+		base := strings.TrimSuffix(name, filepath.Ext(name))
+		ext := filepath.Ext(name)
+		name = base + s.A + ext
+	case OpPrepend:
+		base := strings.TrimSuffix(name, filepath.Ext(name))
+		ext := filepath.Ext(name)
+		name = s.A + base + ext
+	case OpRegexReplace:
+		if s.A != "" {
+			if re, err := compileRegex(s.A, !s.IgnoreCase); err == nil {
+				name = re.ReplaceAllString(name, s.B)
+			}
+		}
+	case OpNumber:
+		if ctx.counters != nil && ctx.counters[i] != nil {
+			if c, ok := ctx.counters[i][ctx.path]; ok {
+				name = applyNumber(name, s.A, s.Num, c)
+			}
+		}
+	case OpTemplate:
+		if parts, err := parseTemplate(s.A); err == nil && s.A != "" {
+			var c *counter
+			if ctx.counters != nil && ctx.counters[i] != nil {
+				if v, ok := ctx.counters[i][ctx.path]; ok {
+					c = &v
+				}
+			}
+			name = expandTemplate(parts, name, ctx, c)
+		}
+	case OpChangeCase:
+		name = applyCase(name, s.Case)
+	case OpNormalize:
+		name = applyNormalize(name, s.A)
+	case OpSanitize:
+		name = applySanitize(name, s.Sanitize, ctx.profile)
+	case OpChangeExt:
+		base := strings.TrimSuffix(name, filepath.Ext(name))
+		newExt := strings.TrimSpace(s.A)
+		if newExt == "" {
+			name = base
+		} else {
+			if !strings.HasPrefix(newExt, ".") {
+				newExt = "." + newExt
+			}
+			name = base + newExt
+		}
+	}
+	return name
+}
+
+// ValidateStep reports a problem with a step's arguments, e.g. a regex that
+// does not compile or a template with an unknown token. A nil error means
+// the step is usable.
+func ValidateStep(s RenameStep) error {
+	if s.Op == OpSanitize && s.Sanitize.Invalid != "" && !slices.Contains(SanitizeInvalidModes, s.Sanitize.Invalid) {
+		return fmt.Errorf("unknown invalid-character mode %q", s.Sanitize.Invalid)
+	}
+	if s.A == "" {
+		return nil
+	}
+	switch s.Op {
+	case OpRegexReplace:
+		_, err := compileRegex(s.A, !s.IgnoreCase)
+		return err
+	case OpTemplate:
+		_, err := parseTemplate(s.A)
+		return err
+	case OpNormalize:
+		if !slices.Contains(UnicodeModes, s.A) {
+			return fmt.Errorf("unknown mode %q", s.A)
+		}
+	}
+	return nil
+}
+
+// FirstStepError returns the first invalid step's error, prefixed with its
+// position in the pipeline, or nil when every step is usable.
+func FirstStepError(steps []RenameStep) error {
+	for i, s := range steps {
+		if err := ValidateStep(s); err != nil {
+			return fmt.Errorf("step %d (%s): %w", i+1, s.Op, err)
+		}
+	}
+	return nil
+}
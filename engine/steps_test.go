@@ -0,0 +1,39 @@
+package engine
+
+import "testing"
+
+func TestRegexReplaceStep(t *testing.T) {
+	tests := []struct {
+		name string
+		step RenameStep
+		in   string
+		want string
+	}{
+		{"numbered group", RenameStep{Op: OpRegexReplace, A: `^IMG_(\d+)`, B: "Photo_$1"}, "IMG_0042.jpg", "Photo_0042.jpg"},
+		{"named group", RenameStep{Op: OpRegexReplace, A: `(?P<y>\d{4})-(?P<m>\d\d)`, B: "${m}.${y}"}, "2024-06 trip.jpg", "06.2024 trip.jpg"},
+		{"group next to text", RenameStep{Op: OpRegexReplace, A: `(\w+)\.jpg$`, B: "${1}_x.jpg"}, "a.jpg", "a_x.jpg"},
+		{"every match", RenameStep{Op: OpRegexReplace, A: `\s+`, B: "_"}, "a  b c.txt", "a_b_c.txt"},
+		{"match case", RenameStep{Op: OpRegexReplace, A: `^img`, B: "pic"}, "IMG_1.jpg", "IMG_1.jpg"},
+		{"ignore case", RenameStep{Op: OpRegexReplace, A: `^img`, B: "pic", IgnoreCase: true}, "IMG_1.jpg", "pic_1.jpg"},
+		{"invalid pattern", RenameStep{Op: OpRegexReplace, A: `(`, B: "x"}, "a.txt", "a.txt"},
+		{"empty pattern", RenameStep{Op: OpRegexReplace, B: "x"}, "a.txt", "a.txt"},
+	}
+	for _, tc := range tests {
+		if got := ApplyRenameSteps(tc.in, []RenameStep{tc.step}); got != tc.want {
+			t.Errorf("%s: %q → %q, want %q", tc.name, tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestValidateRegexStep(t *testing.T) {
+	for _, pattern := range []string{`(`, `[a-`, `a{2,1}`, `(?P<>x)`} {
+		for _, ignoreCase := range []bool{false, true} {
+			if ValidateStep(RenameStep{Op: OpRegexReplace, A: pattern, IgnoreCase: ignoreCase}) == nil {
+				t.Errorf("%q (ignore case %v): no error", pattern, ignoreCase)
+			}
+		}
+	}
+	if err := ValidateStep(RenameStep{Op: OpRegexReplace, A: `^(\d+)`}); err != nil {
+		t.Errorf("valid pattern: %v", err)
+	}
+}
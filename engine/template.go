@@ -0,0 +1,179 @@
+package engine
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/* -------------------- Templates -------------------- */
+
+// Template tokens, e.g. "{parent}_{date:2006-01-02}_{n:03}{ext}":
+//
+//	{name}          base name without extension
+//	{ext}           extension including the dot
+//	{parent}        name of the containing folder
+//	{n} {n:03}      counter in natural name order, optionally zero padded
+//	{size} {size:h} size in bytes, or human readable (1.4MB)
+//	{mtime:LAYOUT}  modification time as a Go time layout (default 2006-01-02)
+//	{date:LAYOUT}   alias of mtime
+//	{ctime:LAYOUT}  creation time where the filesystem records it
+//
+// "{{" and "}}" produce literal braces.
+var templateTokens = map[string]bool{
+	"name": true, "ext": true, "parent": true, "n": true,
+	"size": true, "mtime": true, "date": true, "ctime": true,
+}
+
+const defaultDateLayout = "2006-01-02"
+
+type tmplPart struct {
+	literal string
+	token   string // "" for literal text
+	arg     string // text after the colon, e.g. the date layout
+}
+
+var (
+	templateMu    sync.Mutex
+	templateCache = map[string][]tmplPart{}
+)
+
+// parseTemplate splits src into literal text and tokens, reporting unknown
+// tokens and unbalanced braces. Results are cached like compiled regexes.
+func parseTemplate(src string) ([]tmplPart, error) {
+	templateMu.Lock()
+	if parts, ok := templateCache[src]; ok {
+		templateMu.Unlock()
+		return parts, nil
+	}
+	templateMu.Unlock()
+
+	var parts []tmplPart
+	var lit strings.Builder
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == '{' && i+1 < len(src) && src[i+1] == '{':
+			lit.WriteByte('{')
+			i++
+		case c == '}' && i+1 < len(src) && src[i+1] == '}':
+			lit.WriteByte('}')
+			i++
+		case c == '}':
+			return nil, fmt.Errorf(`unmatched "}" at position %d (use "}}" for a literal brace)`, i+1)
+		case c == '{':
+			end := strings.IndexByte(src[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf(`unclosed "{" at position %d`, i+1)
+			}
+			body := src[i+1 : i+end]
+			tok, arg, _ := strings.Cut(body, ":")
+			tok = strings.TrimSpace(tok)
+			if !templateTokens[tok] {
+				return nil, fmt.Errorf("unknown token {%s}", body)
+			}
+			if tok == "n" && arg != "" {
+				if _, err := strconv.Atoi(arg); err != nil {
+					return nil, fmt.Errorf("{%s}: padding must be digits, e.g. {n:03}", body)
+				}
+			}
+			if tok == "size" && arg != "" && arg != "h" {
+				return nil, fmt.Errorf("{%s}: use {size} or {size:h}", body)
+			}
+			if lit.Len() > 0 {
+				parts = append(parts, tmplPart{literal: lit.String()})
+				lit.Reset()
+			}
+			parts = append(parts, tmplPart{token: tok, arg: arg})
+			i += end
+		default:
+			lit.WriteByte(c)
+		}
+	}
+	if lit.Len() > 0 {
+		parts = append(parts, tmplPart{literal: lit.String()})
+	}
+
+	templateMu.Lock()
+	if len(templateCache) >= maxCachedRegex {
+		templateCache = map[string][]tmplPart{}
+	}
+	templateCache[src] = parts
+	templateMu.Unlock()
+	return parts, nil
+}
+
+func templateUsesCounter(parts []tmplPart) bool {
+	for _, p := range parts {
+		if p.token == "n" {
+			return true
+		}
+	}
+	return false
+}
+
+// expandTemplate renders parts for the file currently named name. counter
+// is nil when the file has no sequence number (not part of the batch).
+func expandTemplate(parts []tmplPart, name string, ctx stepContext, c *counter) string {
+	ext := filepath.Ext(name)
+	var b strings.Builder
+	for _, p := range parts {
+		switch p.token {
+		case "":
+			b.WriteString(p.literal)
+		case "name":
+			b.WriteString(strings.TrimSuffix(name, ext))
+		case "ext":
+			b.WriteString(ext)
+		case "parent":
+			if ctx.path != "" {
+				b.WriteString(filepath.Base(filepath.Dir(ctx.path)))
+			}
+		case "n":
+			if c == nil {
+				continue
+			}
+			width, _ := strconv.Atoi(p.arg)
+			b.WriteString(fmt.Sprintf("%0*d", width, c.value))
+		case "size":
+			size := fileSize(ctx.stat())
+			if p.arg == "h" {
+				b.WriteString(HumanSize(size))
+			} else {
+				b.WriteString(strconv.FormatInt(size, 10))
+			}
+		case "mtime", "date":
+			b.WriteString(formatTime(modTime(ctx.stat()), p.arg))
+		case "ctime":
+			b.WriteString(formatTime(ctx.created(), p.arg))
+		}
+	}
+	return b.String()
+}
+
+func formatTime(t time.Time, layout string) string {
+	if t.IsZero() {
+		return ""
+	}
+	if layout == "" {
+		layout = defaultDateLayout
+	}
+	return t.Format(layout)
+}
+
+// HumanSize formats a byte count with a binary unit, e.g. 1.4MB.
+func HumanSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
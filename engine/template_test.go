@@ -0,0 +1,118 @@
+package engine
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTemplate(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []tmplPart
+		err  string // substring of the error, "" for none
+	}{
+		{src: "plain", want: []tmplPart{{literal: "plain"}}},
+		{src: "{name}{ext}", want: []tmplPart{{token: "name"}, {token: "ext"}}},
+		{src: "{parent}_{n:03}", want: []tmplPart{{token: "parent"}, {literal: "_"}, {token: "n", arg: "03"}}},
+		{src: "{date:2006}", want: []tmplPart{{token: "date", arg: "2006"}}},
+		{src: "{mtime:15:04}", want: []tmplPart{{token: "mtime", arg: "15:04"}}},
+		{src: "{ size }", want: []tmplPart{{token: "size"}}},
+		{src: "{{name}}", want: []tmplPart{{literal: "{name}"}}},
+		{src: "{size:h}", want: []tmplPart{{token: "size", arg: "h"}}},
+		{src: "{title}", err: "unknown token {title}"},
+		{src: "{}", err: "unknown token {}"},
+		{src: "{n:x}", err: "padding must be digits"},
+		{src: "{size:kb}", err: "use {size} or {size:h}"},
+		{src: "a}b", err: `unmatched "}" at position 2`},
+		{src: "a{name", err: `unclosed "{" at position 2`},
+	}
+	for _, tc := range tests {
+		got, err := parseTemplate(tc.src)
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("%q: error %v, want %q", tc.src, err, tc.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tc.src, err)
+			continue
+		}
+		if len(got) != len(tc.want) {
+			t.Errorf("%q: %+v, want %+v", tc.src, got, tc.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Errorf("%q: part %d is %+v, want %+v", tc.src, i, got[i], tc.want[i])
+			}
+		}
+	}
+}
+
+func TestExpandTemplate(t *testing.T) {
+	mod := time.Date(2024, 6, 1, 14, 30, 0, 0, time.Local)
+	path := filepath.Join("trips", "Rome", "IMG_1.jpg")
+	created := time.Date(2023, 12, 24, 8, 0, 0, 0, time.Local)
+	ctx := stepContext{
+		path: path,
+		file: func(p string) File {
+			return File{Path: p, Info: fakeInfo{name: "IMG_1.jpg", size: 1536, mod: mod}, Created: created}
+		},
+	}
+	c := &counter{value: 7}
+
+	tests := []struct {
+		src  string
+		c    *counter
+		want string
+	}{
+		{"{name}", c, "IMG_1"},
+		{"{ext}", c, ".jpg"},
+		{"{parent}_{name}{ext}", c, "Rome_IMG_1.jpg"},
+		{"{n}", c, "7"},
+		{"{n:03}", c, "007"},
+		{"{name}{n}", nil, "IMG_1"},
+		{"{size}", c, "1536"},
+		{"{size:h}", c, "1.5KB"},
+		{"{date}", c, "2024-06-01"},
+		{"{mtime:2006.01.02 15h04}", c, "2024.06.01 14h30"},
+		{"{ctime}", c, "2023-12-24"},
+		{"{ctime:15h}", c, "08h"},
+		{"{{{name}}}", c, "{IMG_1}"},
+	}
+	for _, tc := range tests {
+		parts, err := parseTemplate(tc.src)
+		if err != nil {
+			t.Fatalf("%q: %v", tc.src, err)
+		}
+		if got := expandTemplate(parts, "IMG_1.jpg", ctx, tc.c); got != tc.want {
+			t.Errorf("%q → %q, want %q", tc.src, got, tc.want)
+		}
+	}
+
+	// Without a path there is nothing to stat: the file tokens render empty.
+	parts, _ := parseTemplate("{parent}|{date}|{ctime}|{name}")
+	if got := expandTemplate(parts, "a.txt", stepContext{}, nil); got != "|||a" {
+		t.Errorf("no context → %q, want %q", got, "|||a")
+	}
+}
+
+func TestHumanSize(t *testing.T) {
+	tests := map[int64]string{
+		0:       "0B",
+		1023:    "1023B",
+		1024:    "1.0KB",
+		1536:    "1.5KB",
+		1 << 20: "1.0MB",
+		5 << 30: "5.0GB",
+		3 << 40: "3.0TB",
+	}
+	for n, want := range tests {
+		if got := HumanSize(n); got != want {
+			t.Errorf("HumanSize(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
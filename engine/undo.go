@@ -0,0 +1,142 @@
+package engine
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+/* -------------------- Undo CSV -------------------- */
+
+var undoCSVHeader = []string{"old_path", "new_path", "old_name", "new_name", "status", "reason", "backup_path"}
+
+// WriteUndoCSV writes one row per plan item to w.
+func WriteUndoCSV(w io.Writer, plan []RenamePlanItem) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write(undoCSVHeader)
+	for _, it := range plan {
+		_ = cw.Write([]string{it.OldPath, it.NewPath, it.OldName, it.NewName, it.Status, it.Reason, it.BackupPath})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteUndoCSVFile creates (or truncates) path and writes the log to it.
+func WriteUndoCSVFile(path string, plan []RenamePlanItem) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := WriteUndoCSV(f, plan); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadUndoCSV parses a log written by WriteUndoCSV. Columns are found by
+// header name; old_path, new_path and status are required.
+func ReadUndoCSV(r io.Reader) ([]RenamePlanItem, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.New("undo log is empty")
+	}
+	if err != nil {
+		return nil, err
+	}
+	col := map[string]int{}
+	for i, name := range header {
+		// spreadsheet tools may add a UTF-8 byte order mark when re-saving
+		col[strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))] = i
+	}
+	for _, required := range []string{"old_path", "new_path", "status"} {
+		if _, ok := col[required]; !ok {
+			return nil, fmt.Errorf("undo log has no %s column", required)
+		}
+	}
+
+	field := func(rec []string, name string) string {
+		i, ok := col[name]
+		if !ok || i >= len(rec) {
+			return ""
+		}
+		return rec[i]
+	}
+
+	var rows []RenamePlanItem
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, RenamePlanItem{
+			OldPath: field(rec, "old_path"),
+			NewPath: field(rec, "new_path"),
+			OldName: field(rec, "old_name"),
+			NewName: field(rec, "new_name"),
+			Status:  field(rec, "status"),
+			Reason:  field(rec, "reason"),
+
+			BackupPath: field(rec, "backup_path"),
+		})
+	}
+	return rows, nil
+}
+
+// ReadUndoCSVFile opens path and parses it with ReadUndoCSV.
+func ReadUndoCSVFile(path string) ([]RenamePlanItem, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadUndoCSV(f)
+}
+
+// PlanUndo builds the reverse plan for every "renamed" row of an undo log,
+// checked the same way as Plan (source missing, duplicate targets, target
+// exists). A row that overwrote a file also moves its backup back. Rows that
+// can't be reverted stay in the plan as "skip" with a reason; rows that were
+// never renamed are only counted in sum.Ignored.
+func PlanUndo(rows []RenamePlanItem) ([]RenamePlanItem, PlanSummary) {
+	var items []RenamePlanItem
+	var sum PlanSummary
+
+	for _, row := range rows {
+		if row.Status != StatusRenamed {
+			sum.Ignored++
+			continue
+		}
+		items = append(items, RenamePlanItem{
+			OldPath: row.NewPath,
+			NewPath: row.OldPath,
+			OldName: filepath.Base(row.NewPath),
+			NewName: filepath.Base(row.OldPath),
+			Status:  StatusOK,
+		})
+		if row.BackupPath != "" {
+			// the file this rename overwrote goes back once the path is free
+			items = append(items, RenamePlanItem{
+				OldPath: row.BackupPath,
+				NewPath: row.NewPath,
+				OldName: filepath.Base(row.BackupPath),
+				NewName: filepath.Base(row.NewPath),
+				Status:  StatusOK,
+			})
+		}
+	}
+	sum.Total = len(items)
+
+	checkConflicts(items, &sum, CollisionSkip, newPathKeys(CaseFSAuto, nil))
+	return items, sum
+}
@@ -0,0 +1,142 @@
+package engine
+
+import (
+	"bytes"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadUndoCSV(t *testing.T) {
+	tests := []struct {
+		name string
+		csv  string
+		want []RenamePlanItem
+		err  string // substring of the error, "" for none
+	}{
+		{
+			name: "written header",
+			csv:  "old_path,new_path,old_name,new_name,status,reason,backup_path\n/d/a,/d/b,a,b,renamed,,/d/b (backup)\n",
+			want: []RenamePlanItem{{OldPath: "/d/a", NewPath: "/d/b", OldName: "a", NewName: "b", Status: StatusRenamed, BackupPath: "/d/b (backup)"}},
+		},
+		{
+			name: "byte order mark",
+			csv:  "\ufeffold_path,new_path,status\n/d/a,/d/b,renamed\n",
+			want: []RenamePlanItem{{OldPath: "/d/a", NewPath: "/d/b", Status: StatusRenamed}},
+		},
+		{
+			name: "reordered and padded columns",
+			csv:  "status, new_path ,extra,old_path\nrenamed,/d/b,x,/d/a\nskip,/d/d\n",
+			want: []RenamePlanItem{
+				{OldPath: "/d/a", NewPath: "/d/b", Status: StatusRenamed},
+				{NewPath: "/d/d", Status: StatusSkip},
+			},
+		},
+		{name: "missing column", csv: "old_path,status\n/d/a,renamed\n", err: "no new_path column"},
+		{name: "empty", csv: "", err: "undo log is empty"},
+		{name: "bad quoting", csv: "old_path,new_path,status\n\"/d/a,/d/b,renamed\n", err: "quote"},
+	}
+	for _, tc := range tests {
+		got, err := ReadUndoCSV(strings.NewReader(tc.csv))
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("%s: error %v, want %q", tc.name, err, tc.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if len(got) != len(tc.want) {
+			t.Errorf("%s: %+v, want %+v", tc.name, got, tc.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Errorf("%s: row %d is %+v, want %+v", tc.name, i, got[i], tc.want[i])
+			}
+		}
+	}
+}
+
+// undo writes out's log, reads it back and plans its reversal.
+func undo(t *testing.T, out []RenamePlanItem) ([]RenamePlanItem, PlanSummary) {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := WriteUndoCSV(&buf, out); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := ReadUndoCSV(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return PlanUndo(rows)
+}
+
+func TestUndoRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{"a.txt": "A", "b.txt": "B", "c.txt": "C"}
+	writeFiles(t, dir, files)
+
+	skipped := planItem(dir, "c.txt", "d.txt")
+	skipped.Status = StatusSkip
+	out := Apply([]RenamePlanItem{planItem(dir, "a.txt", "b.txt"), planItem(dir, "b.txt", "a.txt"), skipped})
+
+	plan, sum := undo(t, out)
+	if sum.Ignored != 1 || sum.OkCount != 2 {
+		t.Fatalf("summary %+v, want 2 ok and 1 ignored", sum)
+	}
+	Apply(plan)
+	if got := readDir(t, dir); !maps.Equal(got, files) {
+		t.Errorf("folder = %v, want %v", got, files)
+	}
+}
+
+func TestUndoRestoresBackup(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{"new.txt": "NEW", "old.txt": "OLD"}
+	writeFiles(t, dir, files)
+
+	it := planItem(dir, "new.txt", "old.txt")
+	it.BackupPath = filepath.Join(dir, "old (backup).txt")
+	out := Apply([]RenamePlanItem{it})
+
+	plan, sum := undo(t, out)
+	if len(plan) != 2 || sum.OkCount != 2 {
+		t.Fatalf("plan %+v, want the rename and the backup reverted", plan)
+	}
+	Apply(plan)
+	if got := readDir(t, dir); !maps.Equal(got, files) {
+		t.Errorf("folder = %v, want %v", got, files)
+	}
+}
+
+func TestUndoUnrevertableRows(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.txt": "A", "b.txt": "B"})
+	out := Apply([]RenamePlanItem{planItem(dir, "a.txt", "x.txt"), planItem(dir, "b.txt", "y.txt")})
+
+	// since then x.txt has been deleted and a new b.txt has taken y.txt's
+	// old name
+	if err := os.Remove(filepath.Join(dir, "x.txt")); err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, dir, map[string]string{"b.txt": "new B"})
+
+	plan, sum := undo(t, out)
+	if sum.OkCount != 0 || len(plan) != 2 {
+		t.Fatalf("plan %+v, want both rows skipped", plan)
+	}
+	for _, it := range plan {
+		want := map[string]string{"x.txt": ReasonMissing, "y.txt": ReasonTargetExists}[it.OldName]
+		if it.Status != StatusSkip || it.Reason != want {
+			t.Errorf("%s: %s (%s), want skip (%s)", it.OldName, it.Status, it.Reason, want)
+		}
+	}
+	if len(sum.Missing) != 1 || len(sum.TargetExists) != 1 {
+		t.Errorf("summary %+v, want one missing and one taken", sum)
+	}
+}
@@ -0,0 +1,205 @@
+package engine
+
+import (
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+/* -------------------- Unicode normalisation -------------------- */
+
+// Modes of OpNormalize (RenameStep.A). macOS tends to hand out decomposed
+// names (NFD: "e" + combining accent), Windows and Linux composed ones
+// (NFC: "é"); the two look identical but are different names.
+const (
+	UnicodeNFC        = "NFC (composed)"
+	UnicodeNFD        = "NFD (decomposed)"
+	UnicodeNFKC       = "NFKC (compatibility)"
+	UnicodeStripMarks = "Strip diacritics"
+	UnicodeTranslit   = "Transliterate to ASCII"
+)
+
+// UnicodeModes lists the OpNormalize modes in UI order.
+var UnicodeModes = []string{UnicodeNFC, UnicodeNFD, UnicodeNFKC, UnicodeStripMarks, UnicodeTranslit}
+
+// applyNormalize rewrites name according to an OpNormalize mode; an unknown
+// or empty mode is NFC.
+func applyNormalize(name, mode string) string {
+	switch mode {
+	case UnicodeNFD:
+		return norm.NFD.String(name)
+	case UnicodeNFKC:
+		return norm.NFKC.String(name)
+	case UnicodeStripMarks:
+		return stripMarks(name)
+	case UnicodeTranslit:
+		return transliterate(name)
+	default:
+		return norm.NFC.String(name)
+	}
+}
+
+// stripMarks removes diacritics: é → e, ñ → n, Å → A. Letters that are not
+// a base letter plus marks (ß, ø, ł) are left alone; transliterate handles
+// those.
+func stripMarks(s string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	out, _, err := transform.String(t, s)
+	if err != nil {
+		return s
+	}
+	return out
+}
+
+// TranslitScripts names the scripts transliterate has a table for.
+const TranslitScripts = "Latin, Greek, Cyrillic, Armenian, Georgian, Hebrew and Arabic"
+
+// transliterate spells the letters of TranslitScripts in ASCII (Ж → Zh,
+// Ω → O, ß → ss, ש → sh). Hebrew and Arabic are written without their
+// vowels, so only the consonants are spelled out. Other scripts (CJK,
+// Indic, Thai, ...) are left as they are.
+func transliterate(s string) string {
+	s = norm.NFKC.String(s) // ligatures, full-width forms: ﬁ → fi, Ａ → A
+	var b strings.Builder
+	for _, r := range s {
+		if r < 0x80 {
+			b.WriteRune(r)
+			continue
+		}
+		if t, ok := translitTable[r]; ok {
+			b.WriteString(t)
+			continue
+		}
+		if lower, ok := translitTable[unicode.ToLower(r)]; ok && unicode.IsUpper(r) {
+			b.WriteString(upperFirst(lower))
+			continue
+		}
+		if plain := stripMarks(string(r)); plain != string(r) {
+			b.WriteString(transliterate(plain))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func upperFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// translitTable holds lower case letters; capitals are derived from them.
+var translitTable = map[rune]string{
+	// Latin letters that don't decompose
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'ł': "l", 'đ': "d", 'ð': "d",
+	'þ': "th", 'ħ': "h", 'ı': "i", 'ŋ': "ng", 'ĸ': "k", 'ſ': "s",
+	'\u2018': "'", '\u2019': "'", '\u201c': `"`, '\u201d': `"`, '\u2013': "-", '\u2014': "-",
+
+	// Greek
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i",
+	'θ': "th", 'ι': "i", 'κ': "k", 'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x",
+	'ο': "o", 'π': "p", 'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t", 'υ': "y",
+	'φ': "f", 'χ': "ch", 'ψ': "ps", 'ω': "o",
+
+	// Cyrillic
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+	'є': "ye", 'і': "i", 'ї': "yi", 'ґ': "g", 'ў': "u", 'ђ': "dj",
+	'ј': "j", 'љ': "lj", 'њ': "nj", 'ћ': "c", 'џ': "dz", 'ѕ': "dz",
+
+	// Armenian
+	'ա': "a", 'բ': "b", 'գ': "g", 'դ': "d", 'ե': "e", 'զ': "z", 'է': "e",
+	'ը': "y", 'թ': "t", 'ժ': "zh", 'ի': "i", 'լ': "l", 'խ': "kh", 'ծ': "ts",
+	'կ': "k", 'հ': "h", 'ձ': "dz", 'ղ': "gh", 'ճ': "ch", 'մ': "m", 'յ': "y",
+	'ն': "n", 'շ': "sh", 'ո': "o", 'չ': "ch", 'պ': "p", 'ջ': "j", 'ռ': "r",
+	'ս': "s", 'վ': "v", 'տ': "t", 'ր': "r", 'ց': "ts", 'ւ': "v", 'փ': "p",
+	'ք': "k", 'օ': "o", 'ֆ': "f", 'և': "ev",
+
+	// Georgian
+	'ა': "a", 'ბ': "b", 'გ': "g", 'დ': "d", 'ე': "e", 'ვ': "v", 'ზ': "z",
+	'თ': "t", 'ი': "i", 'კ': "k", 'ლ': "l", 'მ': "m", 'ნ': "n", 'ო': "o",
+	'პ': "p", 'ჟ': "zh", 'რ': "r", 'ს': "s", 'ტ': "t", 'უ': "u", 'ფ': "p",
+	'ქ': "k", 'ღ': "gh", 'ყ': "q", 'შ': "sh", 'ჩ': "ch", 'ც': "ts", 'ძ': "dz",
+	'წ': "ts", 'ჭ': "ch", 'ხ': "kh", 'ჯ': "j", 'ჰ': "h",
+
+	// Hebrew
+	'א': "'", 'ב': "b", 'ג': "g", 'ד': "d", 'ה': "h", 'ו': "v", 'ז': "z",
+	'ח': "kh", 'ט': "t", 'י': "y", 'כ': "k", 'ך': "k", 'ל': "l", 'מ': "m",
+	'ם': "m", 'נ': "n", 'ן': "n", 'ס': "s", 'ע': "'", 'פ': "p", 'ף': "p",
+	'צ': "ts", 'ץ': "ts", 'ק': "k", 'ר': "r", 'ש': "sh", 'ת': "t",
+
+	// Arabic, with the Persian letters
+	'ء': "'", 'ا': "a", 'ب': "b", 'ة': "h", 'ت': "t", 'ث': "th", 'ج': "j",
+	'ح': "h", 'خ': "kh", 'د': "d", 'ذ': "dh", 'ر': "r", 'ز': "z", 'س': "s",
+	'ش': "sh", 'ص': "s", 'ض': "d", 'ط': "t", 'ظ': "z", 'ع': "'", 'غ': "gh",
+	'ف': "f", 'ق': "q", 'ك': "k", 'ل': "l", 'م': "m", 'ن': "n", 'ه': "h",
+	'و': "w", 'ى': "a", 'ي': "y", 'پ': "p", 'چ': "ch", 'ژ': "zh", 'ک': "k",
+	'گ': "g", 'ی': "y", '،': ",", '؛': ";", '؟': "?", 'ـ': "",
+	'٠': "0", '١': "1", '٢': "2", '٣': "3", '٤': "4", '٥': "5", '٦': "6", '٧': "7", '٨': "8", '٩': "9",
+	'۰': "0", '۱': "1", '۲': "2", '۳': "3", '۴': "4", '۵': "5", '۶': "6", '۷': "7", '۸': "8", '۹': "9",
+}
+
+/* -------------------- Normalisation collisions -------------------- */
+
+// normalizationWarnings flags "ok" items whose new name differs from the
+// name of another file in the same folder, once the plan has run, only in
+// Unicode normalisation: they look identical, scripts treat them as
+// different, and macOS volumes treat them as the same file. Folders are
+// listed through keys.
+func normalizationWarnings(items []RenamePlanItem, sum *PlanSummary, keys *pathKeys) {
+	// folder -> NFC name -> final names
+	final := map[string]map[string][]string{}
+	add := func(dir, name string) {
+		if final[dir] == nil {
+			final[dir] = map[string][]string{}
+		}
+		key := norm.NFC.String(name)
+		final[dir][key] = append(final[dir][key], name)
+	}
+
+	vacated := map[string]bool{}
+	for _, it := range items {
+		if it.Status == StatusOK {
+			vacated[filepath.Clean(it.OldPath)] = true
+		}
+	}
+	for _, it := range items {
+		if it.Status != StatusOK {
+			continue
+		}
+		dir := filepath.Dir(it.NewPath)
+		if final[dir] == nil {
+			for _, name := range keys.names(dir) {
+				if !vacated[filepath.Join(dir, name)] {
+					add(dir, name)
+				}
+			}
+			if final[dir] == nil {
+				final[dir] = map[string][]string{}
+			}
+		}
+		add(dir, it.NewName)
+	}
+
+	for i, it := range items {
+		if it.Status != StatusOK {
+			continue
+		}
+		for _, other := range final[filepath.Dir(it.NewPath)][norm.NFC.String(it.NewName)] {
+			if other != it.NewName {
+				items[i].Warning = "looks the same as " + other + " (differs only in Unicode normalisation)"
+				sum.Normalization = append(sum.Normalization, it.OldName+" → "+it.NewName)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package engine
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestApplyNormalize(t *testing.T) {
+	const (
+		nfc = "caf\u00e9.txt"
+		nfd = "cafe\u0301.txt"
+	)
+	tests := []struct {
+		in, mode, want string
+	}{
+		{nfd, UnicodeNFC, nfc},
+		{nfd, "", nfc},
+		{nfd, "unknown", nfc},
+		{nfc, UnicodeNFD, nfd},
+		{"ﬁle１.txt", UnicodeNFKC, "file1.txt"},
+		{"ﬁle.txt", UnicodeNFC, "ﬁle.txt"},
+		{nfc, UnicodeStripMarks, "cafe.txt"},
+		{"Жук Straße.txt", UnicodeTranslit, "Zhuk Strasse.txt"},
+	}
+	for _, tc := range tests {
+		if got := applyNormalize(tc.in, tc.mode); got != tc.want {
+			t.Errorf("applyNormalize(%+q, %q) = %+q, want %+q", tc.in, tc.mode, got, tc.want)
+		}
+	}
+}
+
+func TestStripMarks(t *testing.T) {
+	tests := map[string]string{
+		"Crème Brûlée":   "Creme Brulee",
+		"café":          "cafe",
+		"Ångström ñ":     "Angstrom n",
+		"Straße øl łódź": "Straße øl łodz", // not base letter + marks
+		"Ωμέγα":          "Ωμεγα",
+		"plain.txt":      "plain.txt",
+	}
+	for in, want := range tests {
+		if got := stripMarks(in); got != want {
+			t.Errorf("stripMarks(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestTransliterate(t *testing.T) {
+	tests := map[string]string{
+		"Ærøskøbing":     "Aeroskobing",
+		"Œuvre Þing":     "Oeuvre Thing",
+		"Ωμέγα":          "Omega",
+		"Щука ЁЖ":        "Shchuka YoZh",
+		"Київ":           "Kiyiv",
+		"Երևան":          "Erevan",
+		"თბილისი":        "tbilisi",
+		"שלום":           "shlvm",
+		"بيروت":          "byrwt",
+		"فایل ۱۲.txt":    "fayl 12.txt",
+		"‘quote’ – dash": "'quote' - dash",
+		"东京.txt":         "东京.txt",
+	}
+	for in, want := range tests {
+		if got := transliterate(in); got != want {
+			t.Errorf("transliterate(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestNormalizationWarnings(t *testing.T) {
+	const (
+		nfc = "caf\u00e9.txt"
+		nfd = "cafe\u0301.txt"
+	)
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "sub")
+	tests := []struct {
+		name  string
+		disk  []string // besides a.txt and b.txt
+		plan  []RenamePlanItem
+		warns []bool
+	}{
+		{"new name matches a file on disk", []string{nfd},
+			[]RenamePlanItem{planItem(dir, "a.txt", nfc)}, []bool{true}},
+		{"that file is renamed away", []string{nfd},
+			[]RenamePlanItem{planItem(dir, "a.txt", nfc), planItem(dir, nfd, "c.txt")}, []bool{false, false}},
+		{"two new names match each other", nil,
+			[]RenamePlanItem{planItem(dir, "a.txt", nfc), planItem(dir, "b.txt", nfd)}, []bool{true, true}},
+		{"same spelling", []string{nfc},
+			[]RenamePlanItem{planItem(dir, "a.txt", nfc)}, []bool{false}},
+		{"another folder", []string{nfd},
+			[]RenamePlanItem{planItem(dir, "a.txt", filepath.Join("sub", nfc))}, []bool{false}},
+		{"skipped item", []string{nfd},
+			[]RenamePlanItem{{OldPath: filepath.Join(dir, "a.txt"), NewPath: filepath.Join(dir, nfc), NewName: nfc, Status: StatusSkip}}, []bool{false}},
+	}
+	for _, tc := range tests {
+		sizes := map[string]int64{"a.txt": 1, "b.txt": 1}
+		for _, name := range tc.disk {
+			sizes[name] = 1
+		}
+		scan := append(listing(dir, sizes), File{Path: sub, Info: fakeInfo{name: "sub"}})
+		var sum PlanSummary
+		normalizationWarnings(tc.plan, &sum, newPathKeys(CaseFSSensitive, scan))
+
+		flagged := 0
+		for i, it := range tc.plan {
+			if (it.Warning != "") != tc.warns[i] {
+				t.Errorf("%s: %s → %+q warning %q, want %v", tc.name, it.OldName, it.NewName, it.Warning, tc.warns[i])
+			}
+			if tc.warns[i] {
+				flagged++
+			}
+		}
+		if len(sum.Normalization) != flagged {
+			t.Errorf("%s: summary %v, want %d", tc.name, sum.Normalization, flagged)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package engine
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"unicode/utf16"
+)
+
+/* -------------------- Validations -------------------- */
+
+// Naming profiles: the rules of the filesystem the files will live on. The
+// host OS is not always the target (an SD card, a share, a repo cloned on
+// other systems), so the profile is chosen per run.
+const (
+	ProfileLinux    = "Linux (ext4)"
+	ProfileMacOS    = "macOS (APFS)"
+	ProfileWindows  = "Windows (NTFS)"
+	ProfileFAT      = "FAT32 / exFAT (SD cards)"
+	ProfilePortable = "Portable (all systems)"
+)
+
+// Profiles lists the naming profiles in UI order.
+var Profiles = []string{ProfileLinux, ProfileMacOS, ProfileWindows, ProfileFAT, ProfilePortable}
+
+// DefaultProfile is the profile of the host OS.
+func DefaultProfile() string {
+	switch runtime.GOOS {
+	case "windows":
+		return ProfileWindows
+	case "darwin", "ios":
+		return ProfileMacOS
+	default:
+		return ProfileLinux
+	}
+}
+
+type nameRules struct {
+	invalid        string // characters not allowed anywhere in a name
+	control        bool   // control characters (U+0000–U+001F) not allowed
+	reserved       bool   // DOS device names (CON, COM1, …) not allowed
+	trailingDot    bool   // names can't end in a dot or a space
+	maxBytes       int    // longest name in UTF-8 bytes (0: no limit)
+	maxUnits       int    // longest name in UTF-16 code units (0: no limit)
+	maxPathUnits   int    // longest full path in UTF-16 code units (0: no limit)
+	maxPathMessage string
+}
+
+var windowsRules = nameRules{
+	invalid:        `<>:"/\|?*`,
+	control:        true,
+	reserved:       true,
+	trailingDot:    true,
+	maxUnits:       255,
+	maxPathUnits:   259, // MAX_PATH (260) includes the terminating NUL
+	maxPathMessage: "path longer than 260 characters",
+}
+
+var profileRules = map[string]nameRules{
+	ProfileLinux:   {invalid: "/", maxBytes: 255},
+	ProfileMacOS:   {invalid: "/:", maxBytes: 255},
+	ProfileWindows: windowsRules,
+	ProfileFAT:     windowsRules,
+	ProfilePortable: {
+		invalid:        windowsRules.invalid,
+		control:        true,
+		reserved:       true,
+		trailingDot:    true,
+		maxBytes:       255,
+		maxUnits:       255,
+		maxPathUnits:   windowsRules.maxPathUnits,
+		maxPathMessage: windowsRules.maxPathMessage,
+	},
+}
+
+var reservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+func rulesFor(profile string) nameRules {
+	if r, ok := profileRules[profile]; ok {
+		return r
+	}
+	return profileRules[DefaultProfile()]
+}
+
+// InvalidNameReason says why name can't be used on a filesystem following
+// profile ("" picks the host OS), or returns "" when it can.
+func InvalidNameReason(name, profile string) string {
+	r := rulesFor(profile)
+
+	trim := strings.TrimSpace(name)
+	if trim == "" {
+		return "empty name"
+	}
+	if name == "." || name == ".." {
+		return "reserved filename"
+	}
+	if strings.ContainsRune(name, 0) || strings.ContainsAny(name, r.invalid) {
+		return "invalid characters"
+	}
+	if r.control && strings.ContainsFunc(name, func(c rune) bool { return c < 0x20 }) {
+		return "control characters"
+	}
+	if r.reserved {
+		// Windows ignores the extension: "con.txt" is the console too
+		base, _, _ := strings.Cut(trim, ".")
+		if reservedNames[strings.ToUpper(strings.TrimSpace(base))] {
+			return "reserved filename"
+		}
+	}
+	if r.trailingDot && (strings.HasSuffix(name, ".") || strings.HasSuffix(name, " ")) {
+		return "ends with a dot or space"
+	}
+	if r.maxBytes > 0 && len(name) > r.maxBytes {
+		return fmt.Sprintf("name longer than %d bytes", r.maxBytes)
+	}
+	if r.maxUnits > 0 && len(utf16.Encode([]rune(name))) > r.maxUnits {
+		return fmt.Sprintf("name longer than %d characters", r.maxUnits)
+	}
+	return ""
+}
+
+// InvalidPathReason is InvalidNameReason for name plus the profile's limit
+// on the full path of name in dir. name is checked whole, so a separator in
+// it is invalid rather than a subfolder.
+func InvalidPathReason(dir, name, profile string) string {
+	if reason := InvalidNameReason(name, profile); reason != "" {
+		return reason
+	}
+	r := rulesFor(profile)
+	if r.maxPathUnits > 0 {
+		if abs, err := filepath.Abs(filepath.Join(dir, name)); err == nil && len(utf16.Encode([]rune(abs))) > r.maxPathUnits {
+			return r.maxPathMessage
+		}
+	}
+	return ""
+}
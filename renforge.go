@@ -1,101 +1,113 @@
 package main
 
 import (
-	"encoding/csv"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
+	"io"
+	"maps"
+	"path"
 	"path/filepath"
-	"sort"
+	"runtime"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
+	"FileRenUtil/engine"
+
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 )
 
-/* -------------------- Filters -------------------- */
-
-type FilterRule struct {
-	ID    int
-	Mode  string // "contains", "starts with", "ends with", "extension"
-	Value string
-}
-
-/* -------------------- Rename Steps -------------------- */
-
-type RenameOp string
-
-const (
-	OpRemoveText      RenameOp = "Remove text"
-	OpReplaceText     RenameOp = "Replace text"
-	OpInsertBeforeExt RenameOp = "Insert before extension"
-	OpChangeExt       RenameOp = "Change extension"
-	OpAppend          RenameOp = "Append"
-	OpPrepend         RenameOp = "Prepend"
-)
-
-type RenameStep struct {
-	ID int
-	Op RenameOp
-	A  string
-	B  string
-}
-
 /* -------------------- App State -------------------- */
 
 type AppState struct {
 	folderPath string
 	recursive  bool
+	scan       engine.ScanOptions
+	// the running background scan: scanCancel stops it, and results from
+	// any scan but number scanGen are dropped
+	scanCancel context.CancelFunc
+	scanGen    int
 
-	allFiles      []string
+	allFiles      []engine.File
 	filteredFiles []string
-	viewFiles     []string
-
-	page     int
-	pageSize int
-
-	filters       []FilterRule
+	// the filteredFiles the preview lists (see view), in its sort order
+	viewFiles []string
+	// info captured by the scan, for the size and date columns
+	fileByPath map[string]engine.File
+	// preview column sorted by (one of previewColumns; "" is path order)
+	sortCol  string
+	sortDesc bool
+	// which matches the preview lists (previewViews; "" is all of them)
+	view string
+	// the file the step inspector follows; "" until a row is picked
+	inspected string
+
+	filters       engine.FilterGroup // root group; its MatchAll is the Match ALL/ANY switch
 	nextFilterID  int
-	matchAll      bool
 	caseSensitive bool
+	// compare filters in NFKC so NFD and NFC names match alike
+	normalizeMatch bool
 
-	steps      []RenameStep
+	steps      []engine.RenameStep
 	nextStepID int
 
-	// key: "dir\x00previewName" -> count among selected files in that dir
-	previewCounts map[string]int
+	// what to do when a target is taken (engine.CollisionPolicies)
+	collision string
+	// naming rules of the target filesystem (engine.Profiles)
+	profile string
+	// whether the folder treats names differing in case as one (engine.CaseFSModes)
+	caseFS string
+
+	// selected path -> planned rename, for the preview's conflict warnings
+	plan map[string]engine.RenamePlanItem
+	// rename context (sequence numbers) for the selected files, built for
+	// the steps and profile in pipeline and, when numbering, batchFiles
+	batch      *engine.Batch
+	pipeline   string
+	batchFiles []string
+	// the pending preview recomputation: the timer waits out a burst of
+	// edits, and results of any but number previewGen are dropped. One job
+	// runs at a time: previewCancel stops it, and previewQueued starts
+	// another once it has stopped
+	previewTimer  *time.Timer
+	previewGen    int
+	previewCancel context.CancelFunc
+	previewQueued bool
+	// IDs of the steps that change none of the matches
+	noopSteps map[int]bool
 	// paths the user has explicitly excluded from the apply operation
 	deselected map[string]bool
 }
 
-type RenamePlanItem struct {
-	OldPath string
-	NewPath string
-	OldName string
-	NewName string
-	Status  string // "ok" | "skip" | "renamed" | "error" | "dry-run"
-	Reason  string
-}
-
 const (
 	recentFoldersKey = "recent_folders"
 	maxRecentFolders = 5
+	presetsKey       = "presets"
 )
 
 func main() {
+	runCLIIfRequested()
+
 	a := app.NewWithID("com.blackarck.renforge")
 	w := a.NewWindow("File Rename Utility")
 	w.Resize(fyne.NewSize(1040, 680))
 
 	state := &AppState{
-		pageSize:      10,
-		matchAll:      true,
-		previewCounts: map[string]int{},
-		deselected:    map[string]bool{},
+		filters:    engine.FilterGroup{MatchAll: true},
+		plan:       map[string]engine.RenamePlanItem{},
+		batch:      engine.NewBatch(nil, nil, nil, ""),
+		deselected: map[string]bool{},
+		profile:    engine.DefaultProfile(),
 	}
 
 	/* -------------------- Recent Folders -------------------- */
@@ -110,6 +122,15 @@ func main() {
 
 	// forward declaration so saveRecentFolder can reference it after creation
 	var recentSelect *widget.Select
+	// forward declarations so loading a preset can change the scan (built in
+	// the top bar)
+	var recursiveCheck *widget.Check
+	var setScanOptions func(so engine.ScanOptions, rescan bool)
+	// forward declaration so Apply can rescan the folder (built with the top bar)
+	var startScan func(fresh bool, onDone func())
+	// step ID -> its "no-op" badge, filled in by renderSteps and shown or
+	// hidden as the preview is recomputed
+	stepBadges := map[int]*widget.Label{}
 
 	saveRecentFolder := func(path string) {
 		recents := getRecentFolders()
@@ -136,19 +157,6 @@ func main() {
 	resultsHeader := widget.NewLabel("No folder selected.")
 	resultsHeader.TextStyle = fyne.TextStyle{Bold: true}
 
-	previewBox := container.NewVBox()
-
-	makeCell := func(text string) *widget.RichText {
-		rt := widget.NewRichText(&widget.TextSegment{
-			Text: text,
-			Style: widget.RichTextStyle{
-				SizeName: theme.SizeNameCaptionText,
-			},
-		})
-		rt.Wrapping = fyne.TextWrapWord
-		return rt
-	}
-
 	selCount := func() int {
 		n := 0
 		for _, p := range state.filteredFiles {
@@ -159,138 +167,321 @@ func main() {
 		return n
 	}
 
-	// forward declaration: renderPreview and updatePageView reference each other
-	var updatePageView func()
-
-	renderPreview := func() {
-		previewBox.Objects = nil
-
-		h0 := widget.NewLabelWithStyle("✓", fyne.TextAlignCenter, fyne.TextStyle{Bold: true})
-		h1 := widget.NewLabelWithStyle("Original (full file name)", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
-		h2 := widget.NewLabelWithStyle("Preview (after rename steps)", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
-		previewBox.Add(container.NewGridWithColumns(3, h0, h1, h2))
-		previewBox.Add(widget.NewSeparator())
-
-		for _, full := range state.viewFiles {
-			full := full // per-iteration variable for closure
-			origName := filepath.Base(full)
-			prevName := applyRenameSteps(origName, state.steps)
-
-			warn := ""
-			if reason := invalidNameReason(prevName); reason != "" {
-				warn = "  ⚠ " + reason
-			} else if !state.deselected[full] {
-				// only show conflict warning for selected files
-				dirKey := filepath.Dir(full) + "\x00" + prevName
-				if state.previewCounts[dirKey] > 1 && prevName != origName {
-					warn = "  ⚠ conflict"
-				} else if prevName != origName {
-					target := filepath.Join(filepath.Dir(full), prevName)
-					if _, err := os.Stat(target); err == nil {
-						warn = "  ⚠ target exists"
-					}
+	// forward declaration: the table's checkboxes refresh the view they are in
+	var updatePreview func()
+
+	// stopPreview drops the pending recomputation and cancels the running
+	// one; what it would have shown is out of date.
+	stopPreview := func() {
+		if state.previewTimer != nil {
+			state.previewTimer.Stop()
+		}
+		if state.previewCancel != nil {
+			state.previewCancel()
+		}
+		state.previewGen++
+	}
+
+	// startPreview runs a preview job for the current state in the
+	// background, or, while the last one is still winding down, queues one
+	// to start when it has.
+	var startPreview func()
+	startPreview = func() {
+		if state.previewCancel != nil {
+			state.previewQueued = true
+			return
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		state.previewCancel = cancel
+		gen := state.previewGen
+		job := newPreviewJob(state)
+		go func() {
+			res, err := job.run(ctx)
+			fyne.Do(func() {
+				cancel()
+				state.previewCancel = nil
+				if err == nil && gen == state.previewGen {
+					res.store(state)
+					updatePreview()
 				}
-			}
+				if state.previewQueued {
+					state.previewQueued = false
+					startPreview()
+				}
+			})
+		}()
+	}
 
-			chk := widget.NewCheck("", func(checked bool) {
-				if checked {
-					delete(state.deselected, full)
-				} else {
-					state.deselected[full] = true
+	// refreshPreview recomputes the preview off the UI goroutine once edits
+	// have paused for previewDelay. The table shows the previous result
+	// meanwhile, and a job overtaken by a newer edit is cancelled.
+	refreshPreview := func() {
+		stopPreview()
+		gen := state.previewGen
+		state.previewTimer = time.AfterFunc(previewDelay, func() {
+			fyne.Do(func() {
+				if gen == state.previewGen {
+					startPreview()
 				}
-				recomputePreviewCounts(state)
-				updatePageView()
 			})
-			chk.Checked = !state.deselected[full] // direct field set; SetChecked would trigger OnChanged → infinite loop
+		})
+	}
 
-			previewBox.Add(container.NewGridWithColumns(3,
-				chk,
-				makeCell(origName),
-				makeCell(prevName+warn),
-			))
-			previewBox.Add(widget.NewSeparator())
+	// syncPreview brings the preview up to date at once, for actions that
+	// must not work from a plan a pending edit is about to replace.
+	syncPreview := func() {
+		stopPreview()
+		state.previewQueued = false
+		res, _ := newPreviewJob(state).run(context.Background())
+		res.store(state)
+		updatePreview()
+	}
+
+	// The table only creates cells for the rows on screen and reuses them as
+	// it scrolls, so every match is one scroll away whatever the count.
+	var columns []string
+	var stepErr error // a broken step (e.g. unknown template token) affects every row
+	previewTable := widget.NewTable(
+		func() (int, int) { return len(state.viewFiles), len(columns) },
+		func() fyne.CanvasObject {
+			lbl := widget.NewLabel("")
+			lbl.Truncation = fyne.TextTruncateEllipsis
+			diff := widget.NewRichText()
+			diff.Truncation = fyne.TextTruncateEllipsis
+			return container.NewStack(widget.NewCheck("", nil), lbl, diff)
+		},
+		func(id widget.TableCellID, o fyne.CanvasObject) {
+			cell := o.(*fyne.Container)
+			chk := cell.Objects[0].(*widget.Check)
+			lbl := cell.Objects[1].(*widget.Label)
+			diff := cell.Objects[2].(*widget.RichText)
+			if id.Row >= len(state.viewFiles) || id.Col >= len(columns) {
+				return
+			}
+			full := state.viewFiles[id.Row]
+
+			if columns[id.Col] == colCheck {
+				lbl.Hide()
+				diff.Hide()
+				chk.OnChanged = nil // the cell is recycled; don't toggle the file it showed before
+				chk.SetChecked(!state.deselected[full])
+				chk.OnChanged = func(checked bool) {
+					if checked {
+						delete(state.deselected, full)
+					} else {
+						state.deselected[full] = true
+					}
+					updatePreview()
+					refreshPreview()
+				}
+				chk.Show()
+				return
+			}
+
+			chk.Hide()
+			if columns[id.Col] == colPreview {
+				lbl.Hide()
+				name, _ := previewRow(state, full, stepErr)
+				diff.Segments = diffSegments(filepath.Base(full), name)
+				diff.Refresh()
+				diff.Show()
+				return
+			}
+
+			diff.Hide()
+			text := previewCell(state, full, columns[id.Col], stepErr)
+			lbl.Importance = widget.MediumImportance
+			if strings.HasPrefix(text, "⚠") {
+				lbl.Importance = widget.DangerImportance
+			}
+			lbl.SetText(text)
+			lbl.Show()
+		},
+	)
+
+	// the header row sorts: tap a column once for ascending, again for
+	// descending and a third time to go back to path order
+	previewTable.ShowHeaderRow = true
+	previewTable.CreateHeader = func() fyne.CanvasObject {
+		b := widget.NewButton("", nil)
+		b.Alignment = widget.ButtonAlignLeading
+		b.Importance = widget.LowImportance
+		return b
+	}
+	previewTable.UpdateHeader = func(id widget.TableCellID, o fyne.CanvasObject) {
+		b := o.(*widget.Button)
+		if id.Col < 0 || id.Col >= len(columns) {
+			return
+		}
+		col := columns[id.Col]
+		title := col
+		if col == colCheck {
+			b.SetText("✓")
+			b.OnTapped = nil
+			return
+		}
+		if state.sortCol == col {
+			title += map[bool]string{false: " ▲", true: " ▼"}[state.sortDesc]
+		}
+		b.SetText(title)
+		b.OnTapped = func() {
+			switch {
+			case state.sortCol != col:
+				state.sortCol, state.sortDesc = col, false
+			case !state.sortDesc:
+				state.sortDesc = true
+			default:
+				state.sortCol, state.sortDesc = "", false
+			}
+			updatePreview()
 		}
-		previewBox.Refresh()
 	}
 
-	/* -------------------- Pagination -------------------- */
+	/* -------------------- Step inspector -------------------- */
 
-	prevBtn := widget.NewButton("Previous", nil)
-	nextBtn := widget.NewButton("Next", nil)
-	pageLabel := widget.NewLabel("Page 1/1")
-	pageLabel.Alignment = fyne.TextAlignCenter
+	// The inspector follows the file picked in the table through the
+	// pipeline: the name after each step, as a change to the name before it.
+	inspectTitle := widget.NewLabelWithStyle("Step by step", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	inspectBox := container.NewVBox()
 
-	updatePageView = func() {
-		totalMatches := len(state.filteredFiles)
-		totalFiles := len(state.allFiles)
+	renderInspector := func() {
+		inspectBox.Objects = nil
+		defer inspectBox.Refresh()
+
+		full := state.inspected
+		if _, ok := state.fileByPath[full]; !ok {
+			inspectTitle.SetText("Step by step")
+			inspectBox.Add(widget.NewLabel("Click a file in the preview to see what each rename step does to it."))
+			return
+		}
+		inspectTitle.SetText("Step by step: " + filepath.Base(full))
+		if len(state.steps) == 0 {
+			inspectBox.Add(widget.NewLabel("No rename steps."))
+			return
+		}
 
-		pages := pageCount(totalMatches, state.pageSize)
-		state.page = clamp(state.page, 0, pages-1)
+		stepLabel := func(text string) *widget.Label {
+			return widget.NewLabelWithStyle(text, fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+		}
+		prev := filepath.Base(full)
+		for i, name := range state.batch.Trace(full) {
+			if i >= len(state.steps) {
+				break // a step was removed and the preview hasn't caught up yet
+			}
+			step := state.steps[i]
+			var result fyne.CanvasObject
+			if name == prev {
+				l := widget.NewLabel("no effect")
+				l.Importance = widget.LowImportance
+				result = l
+			} else {
+				result = widget.NewRichText(diffSegments(prev, name)...)
+			}
+			inspectBox.Add(container.NewGridWithColumns(2, stepLabel(fmt.Sprintf("%d. %s", i+1, step.Op)), result))
+			prev = name
+		}
 
-		start := state.page * state.pageSize
-		end := start + state.pageSize
-		if start > totalMatches {
-			start = totalMatches
+		// what Apply would call it: trimmed, and after the collision policy
+		if final, status := previewRow(state, full, engine.FirstStepError(state.steps)); final != prev {
+			inspectBox.Add(container.NewGridWithColumns(2, stepLabel("Final name"), widget.NewRichText(diffSegments(prev, final)...)))
+		} else {
+			inspectBox.Add(container.NewGridWithColumns(2, stepLabel("Final name"), widget.NewLabel(final+"  ("+status+")")))
 		}
-		if end > totalMatches {
-			end = totalMatches
+	}
+
+	// the highlighted row, so it can be let go when sorting or filtering
+	// moves another file under it
+	selectedRow := -1
+	previewTable.OnSelected = func(id widget.TableCellID) {
+		if id.Row < 0 || id.Row >= len(state.viewFiles) {
+			return
 		}
+		selectedRow = id.Row
+		state.inspected = state.viewFiles[id.Row]
+		renderInspector()
+	}
 
-		state.viewFiles = state.filteredFiles[start:end]
+	updatePreview = func() {
+		totalMatches := len(state.filteredFiles)
+		totalFiles := len(state.allFiles)
+
+		stepErr = engine.FirstStepError(state.steps)
+		arrangePreview(state, stepErr)
 
-		sel := selCount()
 		if totalMatches == 0 {
 			resultsHeader.SetText(fmt.Sprintf("No matches (0 of %d files).", totalFiles))
+		} else if shown := len(state.viewFiles); shown < totalMatches {
+			resultsHeader.SetText(fmt.Sprintf(
+				"Showing %d of %d matches · %d selected · %d total files.",
+				shown, totalMatches, selCount(), totalFiles,
+			))
 		} else {
 			resultsHeader.SetText(fmt.Sprintf(
-				"Showing %d–%d of %d matches · %d selected · %d total files.",
-				start+1, end, totalMatches, sel, totalFiles,
+				"%d matches · %d selected · %d total files.",
+				totalMatches, selCount(), totalFiles,
 			))
 		}
 
-		pageLabel.SetText(fmt.Sprintf("Page %d/%d", state.page+1, pages))
-
-		prevBtn.Disable()
-		nextBtn.Disable()
-		if state.page > 0 && totalMatches > 0 {
-			prevBtn.Enable()
+		// with subfolders, a folder column tells same-named files apart
+		if cols := previewColumns(state.recursive); !slices.Equal(cols, columns) {
+			columns = cols
+			for i, c := range columns {
+				previewTable.SetColumnWidth(i, previewColumnWidths[c])
+			}
 		}
-		if state.page < pages-1 && totalMatches > 0 {
-			nextBtn.Enable()
+		if selectedRow >= len(state.viewFiles) || (selectedRow >= 0 && state.viewFiles[selectedRow] != state.inspected) {
+			selectedRow = -1
+			previewTable.UnselectAll()
 		}
+		previewTable.Refresh()
+		renderInspector()
 
-		renderPreview()
+		for id, badge := range stepBadges {
+			if state.noopSteps[id] {
+				badge.Show()
+			} else {
+				badge.Hide()
+			}
+		}
 	}
 
-	prevBtn.OnTapped = func() { state.page--; updatePageView() }
-	nextBtn.OnTapped = func() { state.page++; updatePageView() }
-
-	/* -------------------- Select All / Deselect All -------------------- */
+	/* -------------------- Select All / Deselect All / View -------------------- */
 
+	// both act on the rows shown, so e.g. "Problems only" then Deselect All
+	// leaves the problem files out
 	selectAllBtn := widget.NewButton("Select All", func() {
-		state.deselected = map[string]bool{}
-		recomputePreviewCounts(state)
-		updatePageView()
+		for _, p := range state.viewFiles {
+			delete(state.deselected, p)
+		}
+		updatePreview()
+		refreshPreview()
 	})
 
 	deselectAllBtn := widget.NewButton("Deselect All", func() {
-		for _, p := range state.filteredFiles {
+		for _, p := range state.viewFiles {
 			state.deselected[p] = true
 		}
-		recomputePreviewCounts(state)
-		updatePageView()
+		updatePreview()
+		refreshPreview()
 	})
 
+	viewSelect := widget.NewSelect(previewViews, nil)
+	viewSelect.Selected = viewAll
+	viewSelect.OnChanged = func(v string) {
+		state.view = v
+		updatePreview()
+	}
+
 	rightTop := container.NewVBox(
-		container.NewBorder(nil, nil, nil,
-			container.NewHBox(prevBtn, pageLabel, nextBtn),
-			resultsHeader,
+		resultsHeader,
+		container.NewBorder(nil, nil,
+			container.NewHBox(selectAllBtn, deselectAllBtn),
+			container.NewHBox(widget.NewLabel("Show:"), viewSelect),
 		),
-		container.NewHBox(selectAllBtn, deselectAllBtn),
 		widget.NewSeparator(),
 	)
 
-	/* -------------------- Bottom Actions (Dry Run / Apply / Undo CSV) -------------------- */
+	/* -------------------- Bottom Actions (Dry Run / Undo CSV / Profile / Collisions / Apply / Undo from log / Export) -------------------- */
 
 	dryRunCheck := widget.NewCheck("Dry run (don't rename)", nil)
 	dryRunCheck.SetChecked(true)
@@ -298,17 +489,32 @@ func main() {
 	undoLogCheck := widget.NewCheck("Create undo log (CSV)", nil)
 	undoLogCheck.SetChecked(true)
 
-	applyBtn := widget.NewButtonWithIcon("Apply", theme.ConfirmIcon(), func() {
-		if state.folderPath == "" || len(state.filteredFiles) == 0 {
-			dialog.ShowInformation("Nothing to do", "Select a folder and ensure you have matching files.", w)
-			return
-		}
-		if selCount() == 0 {
-			dialog.ShowInformation("Nothing selected", "No files are selected for rename. Use the checkboxes or Select All.", w)
-			return
-		}
+	// Selected is set directly on these: SetSelected would fire OnChanged
+	// and replan before a folder is loaded
+	profileSelect := widget.NewSelect(engine.Profiles, nil)
+	profileSelect.Selected = state.profile
+	profileSelect.OnChanged = func(v string) {
+		state.profile = v
+		refreshPreview()
+	}
 
-		plan, summary := buildPlan(state)
+	caseFSSelect := widget.NewSelect(engine.CaseFSModes, nil)
+	caseFSSelect.Selected = engine.CaseFSAuto
+	caseFSSelect.OnChanged = func(v string) {
+		state.caseFS = v
+		refreshPreview()
+	}
+
+	collisionSelect := widget.NewSelect(engine.CollisionPolicies, nil)
+	collisionSelect.Selected = engine.CollisionSkip
+	collisionSelect.OnChanged = func(v string) {
+		state.collision = v
+		refreshPreview()
+	}
+
+	// runPlan confirms a validated plan, optionally saves the undo CSV, then
+	// either dry-runs or applies it. Shared by Apply and Undo from log.
+	runPlan := func(title string, plan []engine.RenamePlanItem, summary engine.PlanSummary) {
 		msg := buildConfirmMessage(summary)
 
 		doWithOptionalCSV := func(onSaved func(savePath string)) {
@@ -323,7 +529,7 @@ func main() {
 					return
 				}
 				defer uc.Close()
-				if err := writeUndoCSV(uc, plan); err != nil {
+				if err := engine.WriteUndoCSV(uc, plan); err != nil {
 					dialog.ShowError(err, w)
 					onSaved("")
 					return
@@ -334,7 +540,7 @@ func main() {
 			d.Show()
 		}
 
-		confirm := dialog.NewCustomConfirm("Confirm rename", "Proceed", "Cancel",
+		confirm := dialog.NewCustomConfirm(title, "Proceed", "Cancel",
 			container.NewVScroll(widget.NewLabel(msg)),
 			func(ok bool) {
 				if !ok {
@@ -343,145 +549,306 @@ func main() {
 
 				doWithOptionalCSV(func(savedCSV string) {
 					if dryRunCheck.Checked {
-						for i := range plan {
-							if plan[i].Status == "ok" {
-								plan[i].Status = "dry-run"
-							}
-						}
+						engine.MarkDryRun(plan)
 						dialog.ShowInformation("Dry run complete", fmt.Sprintf(
 							"%s\n\nUndo CSV: %s",
-							buildResultMessage(plan, true),
+							engine.FormatResult(plan, true),
 							prettyPath(savedCSV),
 						), w)
 						return
 					}
 
-					applyResults := applyRenames(plan)
+					applyResults := engine.Apply(plan)
 
 					if savedCSV != "" {
-						_ = overwriteUndoCSV(savedCSV, applyResults)
+						_ = engine.WriteUndoCSVFile(savedCSV, applyResults)
 					}
 
 					dialog.ShowInformation("Apply complete", fmt.Sprintf(
 						"%s\n\nUndo CSV: %s",
-						buildResultMessage(applyResults, false),
+						engine.FormatResult(applyResults, false),
 						prettyPath(savedCSV),
 					), w)
 
-					files, err := listAllFiles(state.folderPath, state.recursive)
-					if err == nil {
-						state.allFiles = files
-						applyAll(state)
-						updatePageView()
+					if state.folderPath == "" {
+						return
 					}
+					startScan(false, nil)
 				})
 			},
 			w,
 		)
 		confirm.Resize(fyne.NewSize(700, 420))
 		confirm.Show()
+	}
+
+	applyBtn := widget.NewButtonWithIcon("Apply", theme.ConfirmIcon(), func() {
+		syncPreview() // don't apply the filters and steps from before the last keystroke
+		if state.folderPath == "" || len(state.filteredFiles) == 0 {
+			dialog.ShowInformation("Nothing to do", "Select a folder and ensure you have matching files.", w)
+			return
+		}
+		if selCount() == 0 {
+			dialog.ShowInformation("Nothing selected", "No files are selected for rename. Use the checkboxes or Select All.", w)
+			return
+		}
+
+		plan, summary := buildPlan(state)
+		runPlan("Confirm rename", plan, summary)
 	})
 
-	actionsBar := container.NewBorder(
-		nil, nil,
-		container.NewHBox(dryRunCheck, undoLogCheck),
-		nil,
-		applyBtn,
+	exportScriptBtn := widget.NewButtonWithIcon("Export as script…", theme.DocumentSaveIcon(), func() {
+		syncPreview()
+		if state.folderPath == "" || selCount() == 0 {
+			dialog.ShowInformation("Nothing to export", "Select a folder and at least one matching file.", w)
+			return
+		}
+
+		plan, summary := buildPlan(state)
+		kindSelect := widget.NewSelect(engine.ScriptKinds, nil)
+		kindSelect.SetSelected(defaultScriptKind())
+
+		dialog.ShowForm("Export as script", "Export…", "Cancel",
+			[]*widget.FormItem{
+				widget.NewFormItem("Format", kindSelect),
+				widget.NewFormItem("", widget.NewLabel(fmt.Sprintf(
+					"%d rename(s), %d skipped file(s) as comments.", summary.OkCount, summary.Total-summary.OkCount))),
+			},
+			func(ok bool) {
+				if !ok {
+					return
+				}
+				script, err := engine.ExportScript(plan, kindSelect.Selected)
+				if err != nil {
+					dialog.ShowError(err, w)
+					return
+				}
+				d := dialog.NewFileSave(func(uc fyne.URIWriteCloser, err error) {
+					if err != nil || uc == nil {
+						return
+					}
+					defer uc.Close()
+					if _, err := io.WriteString(uc, script); err != nil {
+						dialog.ShowError(err, w)
+					}
+				}, w)
+				d.SetFileName(fmt.Sprintf("renforge_rename_%s%s",
+					time.Now().Format("20060102_150405"), engine.ScriptExt[kindSelect.Selected]))
+				d.Show()
+			}, w)
+	})
+
+	undoFromLogBtn := widget.NewButtonWithIcon("Undo from log…", theme.ContentUndoIcon(), func() {
+		d := dialog.NewFileOpen(func(rc fyne.URIReadCloser, err error) {
+			if err != nil || rc == nil {
+				return
+			}
+			defer rc.Close()
+
+			rows, err := engine.ReadUndoCSV(rc)
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			plan, summary := engine.PlanUndo(rows)
+			if summary.Total == 0 {
+				dialog.ShowInformation("Nothing to undo", "The log has no renamed files to revert.", w)
+				return
+			}
+			runPlan("Confirm undo", plan, summary)
+		}, w)
+		d.SetFilter(storage.NewExtensionFileFilter([]string{".csv"}))
+		d.Show()
+	})
+
+	actionsBar := container.NewVBox(
+		container.NewHBox(
+			widget.NewLabel("Names for:"), profileSelect, caseFSSelect,
+			widget.NewLabel("On conflict:"), collisionSelect,
+		),
+		container.NewBorder(
+			nil, nil,
+			container.NewHBox(dryRunCheck, undoLogCheck),
+			container.NewHBox(undoFromLogBtn, exportScriptBtn, applyBtn),
+		),
 	)
 
+	inspector := container.NewBorder(inspectTitle, nil, nil, nil, container.NewVScroll(inspectBox))
+	previewSplit := container.NewVSplit(previewTable, inspector)
+	previewSplit.Offset = 0.72
+
 	right := container.NewBorder(
 		rightTop,
 		actionsBar,
 		nil, nil,
-		container.NewVScroll(previewBox),
+		previewSplit,
 	)
 
 	/* -------------------- Left: Filters + Steps -------------------- */
 
-	applyAllUI := func() {
-		applyAll(state)
-		updatePageView()
-	}
-
 	// Filters UI
 	matchModeSelect := widget.NewSelect([]string{"Match ALL (AND)", "Match ANY (OR)"}, func(sel string) {
-		state.matchAll = (sel == "Match ALL (AND)")
-		applyAllUI()
+		state.filters.MatchAll = (sel == "Match ALL (AND)")
+		refreshPreview()
 	})
 	matchModeSelect.SetSelected("Match ALL (AND)")
 
 	caseSensitiveCheck := widget.NewCheck("Case sensitive", func(v bool) {
 		state.caseSensitive = v
-		applyAllUI()
+		refreshPreview()
+	})
+
+	normalizeMatchCheck := widget.NewCheck("Match any Unicode form", func(v bool) {
+		state.normalizeMatch = v
+		refreshPreview()
 	})
 
 	filtersBox := container.NewVBox()
 	var renderFilters func()
-	renderFilters = func() {
-		filtersBox.Objects = nil
-		if len(state.filters) == 0 {
-			filtersBox.Add(widget.NewLabel("No filters added. Add one to narrow down files."))
-			filtersBox.Refresh()
-			return
+
+	// filterRuleRow edits one rule, found by ID wherever it sits in the tree
+	filterRuleRow := func(rule engine.FilterRule) fyne.CanvasObject {
+		rid := rule.ID
+
+		errLabel := newInlineError()
+		validate := func() {
+			if r := state.filters.Rule(rid); r != nil {
+				setInlineError(errLabel, engine.ValidateFilter(*r))
+			}
 		}
 
-		for _, rule := range state.filters {
-			rid := rule.ID
+		valEntry := widget.NewEntry()
+		valEntry.SetText(rule.Value)
+		valEntry.SetPlaceHolder(filterPlaceholder(rule.Mode))
 
-			modeSel := widget.NewSelect([]string{"contains", "starts with", "ends with", "extension"}, func(sel string) {
-				for i := range state.filters {
-					if state.filters[i].ID == rid {
-						state.filters[i].Mode = sel
-						break
-					}
-				}
-				applyAllUI()
-			})
-			modeSel.SetSelected(rule.Mode)
-
-			valEntry := widget.NewEntry()
-			valEntry.SetText(rule.Value)
-			valEntry.SetPlaceHolder(`value… e.g. The, Whale, png`)
-			valEntry.OnChanged = func(s string) {
-				for i := range state.filters {
-					if state.filters[i].ID == rid {
-						state.filters[i].Value = s
-						break
-					}
+		modeSel := widget.NewSelect(engine.FilterModes, func(sel string) {
+			if r := state.filters.Rule(rid); r != nil {
+				r.Mode = sel
+			}
+			valEntry.SetPlaceHolder(filterPlaceholder(sel))
+			validate()
+			refreshPreview()
+		})
+		modeSel.SetSelected(rule.Mode)
+
+		valEntry.OnChanged = func(s string) {
+			if r := state.filters.Rule(rid); r != nil {
+				r.Value = s
+			}
+			validate()
+			refreshPreview()
+		}
+
+		notCheck := widget.NewCheck("NOT", func(v bool) {
+			if r := state.filters.Rule(rid); r != nil {
+				r.Not = v
+			}
+			refreshPreview()
+		})
+		notCheck.Checked = rule.Not
+
+		removeBtn := widget.NewButton("✕", func() {
+			state.filters.Remove(rid)
+			renderFilters()
+			refreshPreview()
+		})
+
+		return container.NewBorder(nil, errLabel, notCheck, removeBtn,
+			container.NewGridWithColumns(2, modeSel, valEntry),
+		)
+	}
+
+	addFilterRule := func(gid int) {
+		if g := state.filters.Group(gid); g != nil {
+			state.nextFilterID++
+			g.Rules = append(g.Rules, engine.FilterRule{ID: state.nextFilterID, Mode: "contains", Value: ""})
+		}
+		renderFilters()
+		refreshPreview()
+	}
+	// new groups start as OR: the usual reason for one is "jpg OR png"
+	addFilterGroup := func(gid int) {
+		if g := state.filters.Group(gid); g != nil {
+			state.nextFilterID++
+			g.Groups = append(g.Groups, engine.FilterGroup{ID: state.nextFilterID})
+		}
+		renderFilters()
+		refreshPreview()
+	}
+
+	// filterGroupBody lays out a group's rules, then its subgroups, each
+	// indented under a header with its own AND/OR, NOT and buttons
+	var filterGroupBody func(g engine.FilterGroup) *fyne.Container
+	filterGroupBody = func(g engine.FilterGroup) *fyne.Container {
+		body := container.NewVBox()
+		for _, rule := range g.Rules {
+			body.Add(filterRuleRow(rule))
+		}
+		for _, sub := range g.Groups {
+			gid := sub.ID
+
+			combSel := widget.NewSelect([]string{"ALL (AND)", "ANY (OR)"}, nil)
+			combSel.Selected = "ANY (OR)"
+			if sub.MatchAll {
+				combSel.Selected = "ALL (AND)"
+			}
+			combSel.OnChanged = func(sel string) {
+				if g := state.filters.Group(gid); g != nil {
+					g.MatchAll = sel == "ALL (AND)"
 				}
-				applyAllUI()
+				refreshPreview()
 			}
 
-			removeBtn := widget.NewButton("✕", func() {
-				next := state.filters[:0]
-				for _, r := range state.filters {
-					if r.ID != rid {
-						next = append(next, r)
-					}
+			notCheck := widget.NewCheck("NOT", func(v bool) {
+				if g := state.filters.Group(gid); g != nil {
+					g.Not = v
 				}
-				state.filters = append([]FilterRule(nil), next...)
-				renderFilters()
-				applyAllUI()
+				refreshPreview()
 			})
-
-			filtersBox.Add(container.NewBorder(nil, nil, nil, removeBtn,
-				container.NewGridWithColumns(2, modeSel, valEntry),
+			notCheck.Checked = sub.Not
+
+			header := container.NewHBox(
+				widget.NewLabel("Group:"), combSel, notCheck,
+				widget.NewButton("+ Rule", func() { addFilterRule(gid) }),
+				widget.NewButton("+ Group", func() { addFilterGroup(gid) }),
+				widget.NewButton("✕", func() {
+					state.filters.Remove(gid)
+					renderFilters()
+					refreshPreview()
+				}),
+			)
+			indent := widget.NewSeparator()
+			body.Add(container.NewBorder(header, nil, indent, nil,
+				container.NewPadded(filterGroupBody(sub)),
 			))
 		}
+		return body
+	}
+
+	renderFilters = func() {
+		filtersBox.Objects = nil
+		if state.filters.Empty() {
+			filtersBox.Add(widget.NewLabel("No filters added. Add one to narrow down files."))
+			filtersBox.Refresh()
+			return
+		}
+		filtersBox.Add(filterGroupBody(state.filters))
 		filtersBox.Refresh()
 	}
 
 	addFilterBtn := widget.NewButton("+ Add filter", func() {
-		state.nextFilterID++
-		state.filters = append(state.filters, FilterRule{ID: state.nextFilterID, Mode: "contains", Value: ""})
-		renderFilters()
-		applyAllUI()
+		addFilterRule(state.filters.ID)
+	})
+
+	addGroupBtn := widget.NewButton("+ Add group", func() {
+		addFilterGroup(state.filters.ID)
 	})
 
 	clearFiltersBtn := widget.NewButton("Clear filters", func() {
-		state.filters = nil
+		state.filters.Rules = nil
+		state.filters.Groups = nil
 		renderFilters()
-		applyAllUI()
+		refreshPreview()
 	})
 
 	// Steps UI
@@ -489,6 +856,7 @@ func main() {
 	var renderSteps func()
 	renderSteps = func() {
 		stepsBox.Objects = nil
+		clear(stepBadges)
 		if len(state.steps) == 0 {
 			stepsBox.Add(widget.NewLabel("No rename steps. Add one to preview name changes."))
 			stepsBox.Refresh()
@@ -498,23 +866,42 @@ func main() {
 		for _, step := range state.steps {
 			sid := step.ID
 
-			opSel := widget.NewSelect([]string{
-				string(OpRemoveText),
-				string(OpReplaceText),
-				string(OpInsertBeforeExt),
-				string(OpChangeExt),
-				string(OpAppend),
-				string(OpPrepend),
-			}, func(sel string) {
+			errLabel := newInlineError()
+			validate := func() {
+				for _, st := range state.steps {
+					if st.ID == sid {
+						setInlineError(errLabel, engine.ValidateStep(st))
+						return
+					}
+				}
+			}
+
+			// Selected is set directly: SetSelected would fire OnChanged, which
+			// re-renders the steps and so loops forever
+			opSel := widget.NewSelect(stepOpOptions(), nil)
+			opSel.Selected = string(step.Op)
+			opSel.OnChanged = func(sel string) {
 				for i := range state.steps {
 					if state.steps[i].ID == sid {
-						state.steps[i].Op = RenameOp(sel)
+						state.steps[i].Op = engine.RenameOp(sel)
+						if state.steps[i].Op == engine.OpNumber && state.steps[i].Num == (engine.NumberingOptions{}) {
+							state.steps[i].Num = engine.DefaultNumbering()
+						}
+						if state.steps[i].Op == engine.OpChangeCase && state.steps[i].Case == (engine.CaseOptions{}) {
+							state.steps[i].Case = engine.DefaultCase()
+						}
+						if state.steps[i].Op == engine.OpNormalize && !slices.Contains(engine.UnicodeModes, state.steps[i].A) {
+							state.steps[i].A = engine.UnicodeNFC
+						}
+						if state.steps[i].Op == engine.OpSanitize && state.steps[i].Sanitize == (engine.SanitizeOptions{}) {
+							state.steps[i].Sanitize = engine.DefaultSanitize()
+						}
 						break
 					}
 				}
-				applyAllUI()
-			})
-			opSel.SetSelected(string(step.Op))
+				renderSteps()
+				refreshPreview()
+			}
 
 			a := widget.NewEntry()
 			a.SetText(step.A)
@@ -526,21 +913,33 @@ func main() {
 			b.Enable()
 
 			switch step.Op {
-			case OpRemoveText:
+			case engine.OpRemoveText:
 				a.SetPlaceHolder(`text to remove (e.g. vivek)`)
 				b.Disable()
-			case OpReplaceText:
+			case engine.OpReplaceText:
 				a.SetPlaceHolder(`find (e.g. vivek)`)
 				b.SetPlaceHolder(`replace with (e.g. Vivek)`)
-			case OpInsertBeforeExt, OpAppend:
+			case engine.OpInsertBeforeExt, engine.OpAppend:
 				a.SetPlaceHolder(`insert (e.g. (awesome))`)
 				b.Disable()
-			case OpPrepend:
+			case engine.OpPrepend:
 				a.SetPlaceHolder(`prepend (e.g. NEW_)`)
 				b.Disable()
-			case OpChangeExt:
+			case engine.OpChangeExt:
 				a.SetPlaceHolder(`new ext (e.g. xyz or .xyz)`)
 				b.Disable()
+			case engine.OpRegexReplace:
+				a.SetPlaceHolder(`pattern (e.g. ^IMG_(\d+))`)
+				b.SetPlaceHolder(`replace with (e.g. Photo_$1)`)
+			case engine.OpNumber:
+				a.SetPlaceHolder(`separator (e.g. _)`)
+				b.Disable()
+			case engine.OpTemplate:
+				a.SetPlaceHolder(`template (e.g. {parent}_{date:2006-01-02}_{n:03}{ext})`)
+				b.Disable()
+			case engine.OpChangeCase:
+				a.Disable()
+				b.Disable()
 			}
 
 			a.OnChanged = func(v string) {
@@ -550,7 +949,8 @@ func main() {
 						break
 					}
 				}
-				applyAllUI()
+				validate()
+				refreshPreview()
 			}
 			b.OnChanged = func(v string) {
 				for i := range state.steps {
@@ -559,7 +959,7 @@ func main() {
 						break
 					}
 				}
-				applyAllUI()
+				refreshPreview()
 			}
 
 			remove := widget.NewButton("✕", func() {
@@ -569,14 +969,86 @@ func main() {
 						next = append(next, s)
 					}
 				}
-				state.steps = append([]RenameStep(nil), next...)
+				state.steps = append([]engine.RenameStep(nil), next...)
 				renderSteps()
-				applyAllUI()
+				refreshPreview()
 			})
 
-			stepsBox.Add(container.NewBorder(nil, nil, nil, remove,
-				container.NewVBox(opSel, container.NewGridWithColumns(2, a, b)),
-			))
+			// flags a step that leaves every match as it was, e.g. a
+			// replace whose text appears in none of the names
+			badge := widget.NewLabel("no-op")
+			badge.Importance = widget.WarningImportance
+			if !state.noopSteps[sid] {
+				badge.Hide()
+			}
+			stepBadges[sid] = badge
+
+			form := container.NewVBox(container.NewBorder(nil, nil, nil, badge, opSel))
+			if step.Op != engine.OpNormalize && step.Op != engine.OpSanitize {
+				form.Add(container.NewGridWithColumns(2, a, b))
+			}
+			if step.Op == engine.OpNumber {
+				form.Add(numberingForm(step.Num, func(num engine.NumberingOptions) {
+					for i := range state.steps {
+						if state.steps[i].ID == sid {
+							state.steps[i].Num = num
+							break
+						}
+					}
+					refreshPreview()
+				}))
+			}
+			if step.Op == engine.OpChangeCase {
+				form.Add(caseForm(step.Case, func(c engine.CaseOptions) {
+					for i := range state.steps {
+						if state.steps[i].ID == sid {
+							state.steps[i].Case = c
+							break
+						}
+					}
+					refreshPreview()
+				}))
+			}
+			if step.Op == engine.OpRegexReplace {
+				matchCase := widget.NewCheck("Match case", nil)
+				matchCase.Checked = !step.IgnoreCase
+				matchCase.OnChanged = func(on bool) {
+					for i := range state.steps {
+						if state.steps[i].ID == sid {
+							state.steps[i].IgnoreCase = !on
+							break
+						}
+					}
+					refreshPreview()
+				}
+				form.Add(matchCase)
+			}
+			if step.Op == engine.OpNormalize {
+				form.Add(unicodeForm(step.A, func(mode string) {
+					for i := range state.steps {
+						if state.steps[i].ID == sid {
+							state.steps[i].A = mode
+							break
+						}
+					}
+					refreshPreview()
+				}))
+			}
+			if step.Op == engine.OpSanitize {
+				form.Add(sanitizeForm(step.Sanitize, func(opts engine.SanitizeOptions) {
+					for i := range state.steps {
+						if state.steps[i].ID == sid {
+							state.steps[i].Sanitize = opts
+							break
+						}
+					}
+					refreshPreview()
+				}))
+			}
+			form.Add(errLabel)
+			validate()
+
+			stepsBox.Add(container.NewBorder(nil, nil, nil, remove, form))
 			stepsBox.Add(widget.NewSeparator())
 		}
 		stepsBox.Refresh()
@@ -584,30 +1056,211 @@ func main() {
 
 	addStepBtn := widget.NewButton("+ Add rename step", func() {
 		state.nextStepID++
-		state.steps = append(state.steps, RenameStep{ID: state.nextStepID, Op: OpReplaceText, A: "", B: ""})
+		state.steps = append(state.steps, engine.RenameStep{ID: state.nextStepID, Op: engine.OpReplaceText, A: "", B: ""})
 		renderSteps()
-		applyAllUI()
+		refreshPreview()
 	})
 
 	clearStepsBtn := widget.NewButton("Clear steps", func() {
 		state.steps = nil
 		renderSteps()
-		applyAllUI()
+		refreshPreview()
 	})
 
-	left := container.NewVScroll(container.NewVBox(
-		widget.NewLabelWithStyle("Filters", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
-		matchModeSelect,
-		caseSensitiveCheck,
-		container.NewHBox(addFilterBtn, clearFiltersBtn),
-		widget.NewSeparator(),
-		filtersBox,
-
-		widget.NewSeparator(),
-		widget.NewLabelWithStyle("Rename preview pipeline", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
-		container.NewHBox(addStepBtn, clearStepsBtn),
-		widget.NewSeparator(),
-		stepsBox,
+	/* -------------------- Presets -------------------- */
+
+	// Presets are stored one JSON document per list entry, so entries this
+	// build can't read (e.g. saved by a newer version) are kept untouched.
+	getPresets := func() []engine.Preset {
+		var ps []engine.Preset
+		for _, raw := range a.Preferences().StringList(presetsKey) {
+			if p, err := engine.UnmarshalPreset([]byte(raw)); err == nil {
+				ps = append(ps, p)
+			}
+		}
+		return ps
+	}
+
+	presetSelect := widget.NewSelect(nil, nil)
+	presetSelect.PlaceHolder = "Choose a preset…"
+
+	refreshPresetSelect := func(selected string) {
+		var names []string
+		for _, p := range getPresets() {
+			names = append(names, p.Name)
+		}
+		presetSelect.SetOptions(names)
+		presetSelect.Selected = selected // direct set: nothing listens to changes
+		presetSelect.Refresh()
+	}
+
+	// removePreset drops every stored preset called name
+	removePreset := func(name string) []string {
+		var keep []string
+		for _, raw := range a.Preferences().StringList(presetsKey) {
+			if p, err := engine.UnmarshalPreset([]byte(raw)); err == nil && p.Name == name {
+				continue
+			}
+			keep = append(keep, raw)
+		}
+		return keep
+	}
+
+	// storePreset adds p to the library, replacing a preset with the same name
+	storePreset := func(p engine.Preset) {
+		data, err := engine.MarshalPreset(p)
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		a.Preferences().SetStringList(presetsKey, append(removePreset(p.Name), string(data)))
+		refreshPresetSelect(p.Name)
+	}
+
+	selectedPreset := func() (engine.Preset, bool) {
+		for _, p := range getPresets() {
+			if p.Name == presetSelect.Selected {
+				return p, true
+			}
+		}
+		return engine.Preset{}, false
+	}
+
+	loadPreset := func(p engine.Preset) {
+		opts := state.options()
+		p.ApplyTo(&opts)
+
+		state.filters = opts.Filters
+		state.nextFilterID = state.filters.Renumber(1) - 1
+		state.steps = opts.Steps
+		state.nextStepID = len(state.steps)
+		state.caseSensitive = opts.CaseSensitive
+		state.normalizeMatch = opts.NormalizeMatch
+
+		if state.filters.MatchAll {
+			matchModeSelect.SetSelected("Match ALL (AND)")
+		} else {
+			matchModeSelect.SetSelected("Match ANY (OR)")
+		}
+		caseSensitiveCheck.SetChecked(state.caseSensitive)
+		normalizeMatchCheck.SetChecked(state.normalizeMatch)
+		renderFilters()
+		renderSteps()
+		// the check rescans the folder when the setting changes; otherwise
+		// the new scan options do
+		setScanOptions(opts.Scan, opts.Recursive == state.recursive)
+		recursiveCheck.SetChecked(opts.Recursive)
+		refreshPreview()
+	}
+
+	savePresetBtn := widget.NewButton("Save…", func() {
+		nameEntry := widget.NewEntry()
+		nameEntry.SetText(presetSelect.Selected)
+		nameEntry.Validator = func(s string) error {
+			if strings.TrimSpace(s) == "" {
+				return errors.New("enter a name")
+			}
+			return nil
+		}
+		dialog.ShowForm("Save preset", "Save", "Cancel",
+			[]*widget.FormItem{widget.NewFormItem("Name", nameEntry)},
+			func(ok bool) {
+				if ok {
+					storePreset(engine.NewPreset(strings.TrimSpace(nameEntry.Text), state.options()))
+				}
+			}, w)
+	})
+
+	loadPresetBtn := widget.NewButton("Load", func() {
+		if p, ok := selectedPreset(); ok {
+			loadPreset(p)
+		}
+	})
+
+	deletePresetBtn := widget.NewButton("Delete", func() {
+		name := presetSelect.Selected
+		if name == "" {
+			return
+		}
+		dialog.ShowConfirm("Delete preset", fmt.Sprintf("Delete preset %q?", name), func(ok bool) {
+			if !ok {
+				return
+			}
+			a.Preferences().SetStringList(presetsKey, removePreset(name))
+			refreshPresetSelect("")
+		}, w)
+	})
+
+	importPresetBtn := widget.NewButton("Import…", func() {
+		d := dialog.NewFileOpen(func(rc fyne.URIReadCloser, err error) {
+			if err != nil || rc == nil {
+				return
+			}
+			defer rc.Close()
+			data, err := io.ReadAll(rc)
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			p, err := engine.UnmarshalPreset(data)
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			storePreset(p)
+			loadPreset(p)
+		}, w)
+		d.SetFilter(storage.NewExtensionFileFilter([]string{".json"}))
+		d.Show()
+	})
+
+	exportPresetBtn := widget.NewButton("Export…", func() {
+		p, ok := selectedPreset()
+		if !ok {
+			dialog.ShowInformation("No preset selected", "Save or choose a preset to export.", w)
+			return
+		}
+		data, err := engine.MarshalPreset(p)
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		d := dialog.NewFileSave(func(uc fyne.URIWriteCloser, err error) {
+			if err != nil || uc == nil {
+				return
+			}
+			defer uc.Close()
+			if _, err := uc.Write(data); err != nil {
+				dialog.ShowError(err, w)
+			}
+		}, w)
+		d.SetFileName(p.Name + ".renforge.json")
+		d.Show()
+	})
+
+	refreshPresetSelect("")
+
+	left := container.NewVScroll(container.NewVBox(
+		widget.NewLabelWithStyle("Presets", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		container.NewBorder(nil, nil, nil,
+			container.NewHBox(loadPresetBtn, savePresetBtn, deletePresetBtn),
+			presetSelect,
+		),
+		container.NewHBox(importPresetBtn, exportPresetBtn),
+
+		widget.NewSeparator(),
+		widget.NewLabelWithStyle("Filters", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		matchModeSelect,
+		container.NewHBox(caseSensitiveCheck, normalizeMatchCheck),
+		container.NewHBox(addFilterBtn, addGroupBtn, clearFiltersBtn),
+		widget.NewSeparator(),
+		filtersBox,
+
+		widget.NewSeparator(),
+		widget.NewLabelWithStyle("Rename preview pipeline", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		container.NewHBox(addStepBtn, clearStepsBtn),
+		widget.NewSeparator(),
+		stepsBox,
 
 		widget.NewSeparator(),
 		widget.NewLabelWithStyle("Tip", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
@@ -625,41 +1278,245 @@ func main() {
 	selectedFolderLabel := widget.NewLabel("Folder: (none)")
 	selectedFolderLabel.Truncation = fyne.TextTruncateEllipsis
 
+	/* -------------------- Background scanning -------------------- */
+
+	scanBar := widget.NewProgressBarInfinite()
+	scanBar.Stop()
+	scanLabel := widget.NewLabel("")
+	scanCancelBtn := widget.NewButton("Cancel", nil)
+	scanStatus := container.NewBorder(nil, nil, scanLabel, scanCancelBtn, scanBar)
+	scanStatus.Hide()
+
+	// startScan lists the current folder in the background, stopping any scan
+	// still running (e.g. of the folder the user just left). Files stream
+	// into the preview as they are found. A fresh scan clears the list and
+	// the deselections at once; otherwise (a refresh after Apply) the old list
+	// stays up until the first files arrive. onDone runs when the scan
+	// completes, not when it is cancelled or replaced.
+	startScan = func(fresh bool, onDone func()) {
+		if state.scanCancel != nil {
+			state.scanCancel()
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		state.scanGen++
+		gen := state.scanGen
+		state.scanCancel = cancel
+		// the batch caches names and file info from the old scan ({size},
+		// {date}, numbering by date); make the next preview build a new one
+		state.pipeline = ""
+
+		if fresh {
+			state.allFiles = nil
+			state.deselected = map[string]bool{}
+			syncPreview()
+		}
+		scanLabel.SetText("Scanning…")
+		scanBar.Start()
+		scanStatus.Show()
+		scanCancelBtn.OnTapped = cancel
+
+		opts := state.options()
+		streamed := false
+		go func() {
+			files, skipped, err := engine.ScanContext(ctx, opts, func(p engine.ScanProgress) {
+				fyne.Do(func() {
+					if gen != state.scanGen {
+						return
+					}
+					if !streamed {
+						state.allFiles = nil
+						streamed = true
+					}
+					state.allFiles = append(state.allFiles, p.New...)
+					scanLabel.SetText(fmt.Sprintf("Scanning… %d files, %d folders", p.Files, p.Dirs))
+					refreshPreview()
+				})
+			})
+			fyne.Do(func() {
+				if gen != state.scanGen {
+					return
+				}
+				cancel()
+				state.scanCancel = nil
+				scanBar.Stop()
+				scanStatus.Hide()
+
+				state.allFiles = files
+				state.pipeline = "" // as above: the batch saw a partial scan
+				refreshPreview()
+				if errors.Is(err, context.Canceled) {
+					dialog.ShowInformation("Scan cancelled",
+						fmt.Sprintf("Showing the %d files found before the scan was cancelled.", len(files)), w)
+					return
+				}
+				if err != nil {
+					dialog.ShowError(err, w)
+					return
+				}
+				if len(skipped) > 0 {
+					lines := make([]string, len(skipped))
+					for i, e := range skipped {
+						lines[i] = e.Error()
+					}
+					msg := container.NewVScroll(widget.NewLabel(strings.Join(lines, "\n")))
+					d := dialog.NewCustom(fmt.Sprintf("%d folder(s) could not be read", len(skipped)), "OK", container.NewBorder(
+						widget.NewLabel("These were skipped; the rest of the folder was scanned:"), nil, nil, nil, msg,
+					), w)
+					d.Resize(fyne.NewSize(700, 360))
+					d.Show()
+				}
+				if onDone != nil {
+					onDone()
+				}
+			})
+		}()
+	}
+
+	rescanFolder := func() {
+		if state.folderPath != "" {
+			startScan(true, nil)
+		}
+	}
+
 	// Recursive toggle — reloads current folder when toggled
-	recursiveCheck := widget.NewCheck("Include subfolders", func(v bool) {
+	recursiveCheck = widget.NewCheck("Include subfolders", func(v bool) {
 		state.recursive = v
-		if state.folderPath == "" {
+		rescanFolder()
+	})
+
+	// Scan options: the button shows what is narrowed down and opens the form
+	scanOptionsBtn := widget.NewButton(scanSummary(state.scan), nil)
+	setScanOptions = func(so engine.ScanOptions, rescan bool) {
+		state.scan = so
+		scanOptionsBtn.SetText(scanSummary(so))
+		if rescan {
+			rescanFolder()
+		}
+	}
+	scanOptionsBtn.OnTapped = func() {
+		depthEntry := widget.NewEntry()
+		depthEntry.SetText(strconv.Itoa(state.scan.MaxDepth))
+		depthEntry.Validator = func(s string) error {
+			if n, err := strconv.Atoi(strings.TrimSpace(s)); err != nil || n < 0 {
+				return errors.New("enter 0 (no limit) or more")
+			}
+			return nil
+		}
+		excludeEntry := widget.NewEntry()
+		excludeEntry.SetPlaceHolder("e.g. .git, node_modules, build/**")
+		excludeEntry.SetText(strings.Join(state.scan.Exclude, ", "))
+		hiddenCheck := widget.NewCheck("Skip dot-files and dot-folders", nil)
+		hiddenCheck.Checked = state.scan.SkipHidden
+		ignoreCheck := widget.NewCheck("Honour .gitignore / .ignore", nil)
+		ignoreCheck.Checked = state.scan.UseIgnore
+		linksCheck := widget.NewCheck("Follow symlinked folders", nil)
+		linksCheck.Checked = state.scan.FollowLinks
+
+		d := dialog.NewForm("Scan options", "Apply", "Cancel", []*widget.FormItem{
+			widget.NewFormItem("Max depth", depthEntry),
+			widget.NewFormItem("Exclude folders", excludeEntry),
+			widget.NewFormItem("", hiddenCheck),
+			widget.NewFormItem("", ignoreCheck),
+			widget.NewFormItem("", linksCheck),
+		}, func(ok bool) {
+			if !ok {
+				return
+			}
+			depth, _ := strconv.Atoi(strings.TrimSpace(depthEntry.Text))
+			so := engine.ScanOptions{
+				MaxDepth:    depth,
+				SkipHidden:  hiddenCheck.Checked,
+				UseIgnore:   ignoreCheck.Checked,
+				FollowLinks: linksCheck.Checked,
+			}
+			for _, pattern := range strings.Split(excludeEntry.Text, ",") {
+				if pattern = strings.TrimSpace(pattern); pattern != "" {
+					so.Exclude = append(so.Exclude, pattern)
+				}
+			}
+			setScanOptions(so, true)
+		}, w)
+		d.Resize(fyne.NewSize(520, 320))
+		d.Show()
+	}
+
+	/* -------------------- Recovery of interrupted renames -------------------- */
+
+	// offerRecovery shows what an interrupted Apply left behind and lets the
+	// user finish (roll forward) or undo (roll back) each batch; onDone runs
+	// after files were moved.
+	offerRecovery := func(rec engine.Recovery, onDone func()) {
+		if rec.Empty() {
 			return
 		}
-		files, err := listAllFiles(state.folderPath, state.recursive)
-		if err != nil {
-			dialog.ShowError(err, w)
+		msg := container.NewVScroll(widget.NewLabel(engine.FormatRecovery(rec)))
+		if len(rec.Journals) == 0 {
+			d := dialog.NewCustom("Leftover temporary files", "OK", msg, w)
+			d.Resize(fyne.NewSize(700, 360))
+			d.Show()
 			return
 		}
-		state.allFiles = files
-		state.deselected = map[string]bool{}
-		applyAll(state)
-		updatePageView()
-	})
+
+		var d *dialog.CustomDialog
+		finish := func(forward bool) {
+			d.Hide()
+			var results []engine.RenamePlanItem
+			var failed []string
+			for _, j := range rec.Journals {
+				out, err := j.Recover(forward)
+				if err != nil {
+					failed = append(failed, err.Error())
+				}
+				results = append(results, out...)
+			}
+			title := "Rolled forward"
+			if !forward {
+				title = "Rolled back"
+			}
+			text := engine.FormatResult(results, false)
+			for _, it := range results {
+				if it.Status == engine.StatusError {
+					failed = append(failed, fmt.Sprintf("%s → %s: %s", it.OldName, it.NewName, it.Reason))
+				}
+			}
+			if len(failed) > 0 {
+				text += "\n\n" + strings.Join(failed, "\n")
+			}
+			dialog.ShowInformation(title, text, w)
+			onDone()
+		}
+		d = dialog.NewCustomWithoutButtons("Interrupted rename found", container.NewBorder(
+			widget.NewLabel("RenForge stopped before finishing a rename. Finish it, or put every file back?"),
+			nil, nil, nil, msg,
+		), w)
+		d.SetButtons([]fyne.CanvasObject{
+			widget.NewButton("Later", func() { d.Hide() }),
+			widget.NewButton("Roll back", func() { finish(false) }),
+			widget.NewButtonWithIcon("Roll forward", theme.ConfirmIcon(), func() { finish(true) }),
+		})
+		d.Resize(fyne.NewSize(700, 420))
+		d.Show()
+	}
 
 	loadFolder := func(path string) {
 		state.folderPath = path
 		selectedFolderLabel.SetText("Folder: " + path)
+		startScan(true, func() { saveRecentFolder(path) })
 
-		files, err := listAllFiles(path, state.recursive)
-		if err != nil {
-			dialog.ShowError(err, w)
-			state.allFiles = nil
-			applyAll(state)
-			updatePageView()
-			return
-		}
-
-		state.allFiles = files
-		state.deselected = map[string]bool{}
-		applyAll(state)
-		updatePageView()
-		saveRecentFolder(path)
+		// a recursive search for leftovers takes as long as the scan, so it
+		// runs in the background too
+		recursive := state.recursive
+		go func() {
+			rec, err := engine.FindRecovery(path, recursive)
+			if err != nil {
+				return
+			}
+			fyne.Do(func() {
+				if state.folderPath == path {
+					offerRecovery(rec, func() { startScan(false, nil) })
+				}
+			})
+		}()
 	}
 
 	// Recent folders dropdown — populated from persisted preferences
@@ -701,7 +1558,7 @@ func main() {
 	})
 
 	topBar := container.NewBorder(nil, nil,
-		container.NewHBox(selectFolderBtn, recentSelect, refreshBtn, recursiveCheck),
+		container.NewHBox(selectFolderBtn, recentSelect, refreshBtn, recursiveCheck, scanOptionsBtn),
 		container.NewHBox(aboutBtn),
 		selectedFolderLabel,
 	)
@@ -711,480 +1568,479 @@ func main() {
 	split := container.NewHSplit(left, right)
 	split.Offset = 0.38
 
-	root := container.NewBorder(topBar, nil, nil, nil, split)
+	root := container.NewBorder(container.NewVBox(topBar, scanStatus), nil, nil, nil, split)
 	w.SetContent(root)
 
-	updatePageView()
+	updatePreview()
+
+	// a crash during Apply can strand files in a recently used folder; a
+	// slow or unreachable share must not hold up the launch, so look in the
+	// background
+	go func() {
+		var pending engine.Recovery
+		seenJournal := map[string]bool{}
+		for _, folder := range initialRecents {
+			rec, err := engine.FindRecovery(folder, false)
+			if err != nil {
+				continue
+			}
+			for _, j := range rec.Journals {
+				if !seenJournal[j.Path] {
+					seenJournal[j.Path] = true
+					pending.Journals = append(pending.Journals, j)
+				}
+			}
+			pending.Orphans = append(pending.Orphans, rec.Orphans...)
+			pending.Errors = append(pending.Errors, rec.Errors...)
+		}
+		fyne.Do(func() {
+			offerRecovery(pending, func() {
+				if state.folderPath != "" {
+					loadFolder(state.folderPath)
+				}
+			})
+		})
+	}()
 
 	w.ShowAndRun()
 }
 
 /* -------------------- Apply Pipeline Helpers -------------------- */
 
-func applyAll(state *AppState) {
-	applyFilters(state)
-	recomputePreviewCounts(state)
-	state.page = 0
-}
-
-func applyFilters(state *AppState) {
-	if len(state.allFiles) == 0 {
-		state.filteredFiles = nil
-		return
-	}
-	state.filteredFiles = filterFilesMulti(state.allFiles, state.filters, state.matchAll, state.caseSensitive)
-}
-
-func recomputePreviewCounts(state *AppState) {
-	counts := make(map[string]int, len(state.filteredFiles))
-	for _, p := range state.filteredFiles {
-		if state.deselected[p] {
-			continue
-		}
-		orig := filepath.Base(p)
-		prev := applyRenameSteps(orig, state.steps)
-		// key by (dir, previewName) so files in different subdirs don't false-conflict
-		counts[filepath.Dir(p)+"\x00"+prev]++
+// options is the engine view of the current UI state.
+func (s *AppState) options() engine.Options {
+	return engine.Options{
+		Folder:         s.folderPath,
+		Recursive:      s.recursive,
+		Scan:           s.scan,
+		Filters:        s.filters,
+		CaseSensitive:  s.caseSensitive,
+		NormalizeMatch: s.normalizeMatch,
+		Steps:          s.steps,
+		Collision:      s.collision,
+		Profile:        s.profile,
+		CaseFS:         s.caseFS,
 	}
-	state.previewCounts = counts
 }
 
-/* -------------------- Plan / Confirm / Apply -------------------- */
-
-type PlanSummary struct {
-	Total        int
-	OkCount      int
-	Unchanged    int
-	Invalid      []string
-	Duplicate    []string
-	TargetExists []string
-}
-
-func buildPlan(state *AppState) ([]RenamePlanItem, PlanSummary) {
-	// operate only on selected (non-deselected) files
+// selectedFiles is the matched files the user hasn't deselected.
+func selectedFiles(state *AppState) []string {
 	var selected []string
 	for _, p := range state.filteredFiles {
 		if !state.deselected[p] {
 			selected = append(selected, p)
 		}
 	}
+	return selected
+}
 
-	items := make([]RenamePlanItem, 0, len(selected))
-
-	// key: "dir\x00newName" -> count, to detect within-dir conflicts
-	dupCount := map[string]int{}
-	previewByPath := map[string]string{}
-
-	for _, p := range selected {
-		newName := applyRenameSteps(filepath.Base(p), state.steps)
-		previewByPath[p] = newName
-		dupCount[filepath.Dir(p)+"\x00"+newName]++
-	}
-
-	var sum PlanSummary
-	sum.Total = len(selected)
-
-	for _, oldPath := range selected {
-		oldName := filepath.Base(oldPath)
-		newName := previewByPath[oldPath]
-		newPath := filepath.Join(filepath.Dir(oldPath), newName)
+/* -------------------- Preview pipeline -------------------- */
 
-		it := RenamePlanItem{
-			OldPath: oldPath,
-			NewPath: newPath,
-			OldName: oldName,
-			NewName: newName,
-			Status:  "ok",
-		}
+// previewDelay is how long edits have to pause before the preview is
+// recomputed, so typing into a step doesn't replan the folder on every key.
+const previewDelay = 150 * time.Millisecond
 
-		if newName == oldName {
-			sum.Unchanged++
-			it.Status = "skip"
-			it.Reason = "unchanged"
-			items = append(items, it)
-			continue
-		}
-
-		if reason := invalidNameReason(newName); reason != "" {
-			sum.Invalid = append(sum.Invalid, fmt.Sprintf("%s → %s (%s)", oldName, newName, reason))
-			it.Status = "skip"
-			it.Reason = "invalid: " + reason
-			items = append(items, it)
-			continue
-		}
+// previewJob is what the preview is computed from, copied on the UI
+// goroutine so the work can run off it while the user keeps editing.
+type previewJob struct {
+	opts       engine.Options
+	all        []engine.File
+	deselected map[string]bool
 
-		if dupCount[filepath.Dir(oldPath)+"\x00"+newName] > 1 {
-			sum.Duplicate = append(sum.Duplicate, fmt.Sprintf("%s → %s", oldName, newName))
-			it.Status = "skip"
-			it.Reason = "conflict: duplicate preview name"
-			items = append(items, it)
-			continue
-		}
+	// the current batch, reused while the pipeline and, for numbering
+	// steps, the selection are unchanged, so its names needn't be redone
+	batch      *engine.Batch
+	pipeline   string
+	batchFiles []string
+}
 
-		if _, err := os.Stat(newPath); err == nil {
-			sum.TargetExists = append(sum.TargetExists, fmt.Sprintf("%s → %s", oldName, newName))
-			it.Status = "skip"
-			it.Reason = "conflict: target exists on disk"
-			items = append(items, it)
-			continue
-		}
+type previewResult struct {
+	filtered   []string
+	fileByPath map[string]engine.File
+	batch      *engine.Batch
+	pipeline   string
+	batchFiles []string
+	plan       map[string]engine.RenamePlanItem
+	noop       map[int]bool
+}
 
-		sum.OkCount++
-		items = append(items, it)
+func newPreviewJob(state *AppState) previewJob {
+	opts := state.options()
+	opts.Filters = opts.Filters.Clone()
+	opts.Steps = slices.Clone(opts.Steps)
+	return previewJob{
+		opts:       opts,
+		all:        state.allFiles[:len(state.allFiles):len(state.allFiles)],
+		deselected: maps.Clone(state.deselected),
+		batch:      state.batch,
+		pipeline:   state.pipeline,
+		batchFiles: state.batchFiles,
 	}
-
-	return items, sum
 }
 
-func buildConfirmMessage(sum PlanSummary) string {
-	var b strings.Builder
-	b.WriteString(fmt.Sprintf("You are about to process %d file(s).\n", sum.Total))
-	b.WriteString(fmt.Sprintf("Will rename: %d\n", sum.OkCount))
-	b.WriteString(fmt.Sprintf("Unchanged (skipped): %d\n\n", sum.Unchanged))
-
-	if len(sum.Invalid) > 0 {
-		b.WriteString("Invalid names (skipped):\n")
-		for _, s := range firstN(sum.Invalid, 20) {
-			b.WriteString(" - " + s + "\n")
+// run filters the scan, renames the selected matches and plans the batch
+// the way Apply would, so the preview warns about exactly what Apply will
+// skip. Targets are checked against the scan rather than the disk; Apply
+// and Export replan against the disk. It gives up between stages once ctx
+// is cancelled.
+func (j previewJob) run(ctx context.Context) (previewResult, error) {
+	var r previewResult
+	if len(j.all) > 0 {
+		r.filtered = engine.Filter(j.all, j.opts)
+		r.fileByPath = make(map[string]engine.File, len(j.all))
+		for _, f := range j.all {
+			r.fileByPath[f.Path] = f
 		}
-		if len(sum.Invalid) > 20 {
-			b.WriteString(fmt.Sprintf(" ... and %d more\n", len(sum.Invalid)-20))
-		}
-		b.WriteString("\n")
 	}
-
-	if len(sum.Duplicate) > 0 {
-		b.WriteString("Duplicate preview conflicts (skipped):\n")
-		for _, s := range firstN(sum.Duplicate, 20) {
-			b.WriteString(" - " + s + "\n")
-		}
-		if len(sum.Duplicate) > 20 {
-			b.WriteString(fmt.Sprintf(" ... and %d more\n", len(sum.Duplicate)-20))
-		}
-		b.WriteString("\n")
+	if err := ctx.Err(); err != nil {
+		return r, err
 	}
 
-	if len(sum.TargetExists) > 0 {
-		b.WriteString("Target already exists on disk (skipped):\n")
-		for _, s := range firstN(sum.TargetExists, 20) {
-			b.WriteString(" - " + s + "\n")
-		}
-		if len(sum.TargetExists) > 20 {
-			b.WriteString(fmt.Sprintf(" ... and %d more\n", len(sum.TargetExists)-20))
+	selected := make([]string, 0, len(r.filtered))
+	for _, p := range r.filtered {
+		if !j.deselected[p] {
+			selected = append(selected, p)
 		}
-		b.WriteString("\n")
 	}
 
-	b.WriteString("Proceed?")
-	return b.String()
-}
-
-// applyRenames uses a two-phase rename to safely handle circular renames
-// (e.g. a→b and b→a). Phase 1 moves every file to a temp name; phase 2
-// moves each temp name to its final destination.
-func applyRenames(plan []RenamePlanItem) []RenamePlanItem {
-	out := make([]RenamePlanItem, len(plan))
-	copy(out, plan)
-
-	type staged struct {
-		idx     int
-		tmpPath string
+	r.pipeline = pipelineKey(j.opts.Steps, j.opts.Profile)
+	r.batch, r.batchFiles = j.batch, j.batchFiles
+	if j.batch == nil || r.pipeline != j.pipeline || (j.batch.Numbered() && !slices.Equal(selected, j.batchFiles)) {
+		r.batch = engine.NewBatch(selected, j.all, j.opts.Steps, j.opts.Profile)
+		r.batchFiles = selected
 	}
-	var phase2 []staged
 
-	ts := time.Now().UnixNano()
-
-	for i := range out {
-		if out[i].Status != "ok" {
-			continue
-		}
-		tmpPath := filepath.Join(filepath.Dir(out[i].OldPath), fmt.Sprintf(".renforge_tmp_%d_%d", ts, i))
-		if err := os.Rename(out[i].OldPath, tmpPath); err != nil {
-			out[i].Status = "error"
-			out[i].Reason = err.Error()
-			continue
-		}
-		phase2 = append(phase2, staged{i, tmpPath})
+	opts := j.opts
+	opts.Listing = j.all
+	items, _ := engine.PlanBatch(r.batch, selected, opts)
+	if err := ctx.Err(); err != nil {
+		return r, err
 	}
-
-	for _, s := range phase2 {
-		if err := os.Rename(s.tmpPath, out[s.idx].NewPath); err != nil {
-			out[s.idx].Status = "error"
-			out[s.idx].Reason = err.Error()
-			// best-effort restore to original name
-			_ = os.Rename(s.tmpPath, out[s.idx].OldPath)
-		} else {
-			out[s.idx].Status = "renamed"
+	r.plan = make(map[string]engine.RenamePlanItem, len(items))
+	for _, it := range items {
+		r.plan[it.OldPath] = it
+	}
+	r.noop = map[int]bool{}
+	for i, noop := range r.batch.NoopSteps(r.filtered) {
+		if noop {
+			r.noop[j.opts.Steps[i].ID] = true
 		}
 	}
-
-	return out
+	return r, ctx.Err()
 }
 
-func buildResultMessage(items []RenamePlanItem, dryRun bool) string {
-	var renamed, skipped, errors int
-	for _, it := range items {
-		switch it.Status {
-		case "renamed":
-			renamed++
-		case "skip":
-			skipped++
-		case "error":
-			errors++
-		case "dry-run":
-			renamed++
-		}
-	}
+func (r previewResult) store(state *AppState) {
+	state.filteredFiles = r.filtered
+	state.fileByPath = r.fileByPath
+	state.batch = r.batch
+	state.pipeline = r.pipeline
+	state.batchFiles = r.batchFiles
+	state.plan = r.plan
+	state.noopSteps = r.noop
+}
 
-	if dryRun {
-		return fmt.Sprintf("Dry run complete.\nWould rename: %d\nSkipped: %d\nErrors: %d", renamed, skipped, errors)
-	}
-	return fmt.Sprintf("Apply complete.\nRenamed: %d\nSkipped: %d\nErrors: %d", renamed, skipped, errors)
+// pipelineKey identifies the steps and profile a batch renames with.
+func pipelineKey(steps []engine.RenameStep, profile string) string {
+	b, _ := json.Marshal(steps)
+	return profile + "\x00" + string(b)
 }
 
-/* -------------------- Undo CSV -------------------- */
+/* -------------------- Plan / Confirm -------------------- */
 
-func writeUndoCSV(wc fyne.URIWriteCloser, plan []RenamePlanItem) error {
-	cw := csv.NewWriter(wc)
-	defer cw.Flush()
-	_ = cw.Write([]string{"old_path", "new_path", "old_name", "new_name", "status", "reason"})
-	for _, it := range plan {
-		_ = cw.Write([]string{it.OldPath, it.NewPath, it.OldName, it.NewName, it.Status, it.Reason})
-	}
-	return cw.Error()
+// buildPlan plans the rename of every selected match.
+func buildPlan(state *AppState) ([]engine.RenamePlanItem, engine.PlanSummary) {
+	return engine.Plan(selectedFiles(state), state.options())
 }
 
-func overwriteUndoCSV(path string, plan []RenamePlanItem) error {
-	f, err := os.Create(path)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-
-	cw := csv.NewWriter(f)
-	defer cw.Flush()
-	_ = cw.Write([]string{"old_path", "new_path", "old_name", "new_name", "status", "reason"})
-	for _, it := range plan {
-		_ = cw.Write([]string{it.OldPath, it.NewPath, it.OldName, it.NewName, it.Status, it.Reason})
-	}
-	return cw.Error()
+func buildConfirmMessage(sum engine.PlanSummary) string {
+	return engine.FormatSummary(sum) + "Proceed?"
 }
 
-/* -------------------- File listing -------------------- */
+/* -------------------- Preview rows -------------------- */
 
-func listAllFiles(folder string, recursive bool) ([]string, error) {
-	var files []string
+// Preview table columns. The folder column only shows with subfolders.
+const (
+	colCheck    = ""
+	colOriginal = "Original"
+	colPreview  = "Preview"
+	colStatus   = "Status"
+	colFolder   = "Folder"
+	colSize     = "Size"
+	colModified = "Modified"
+)
 
+func previewColumns(recursive bool) []string {
 	if recursive {
-		err := filepath.WalkDir(folder, func(path string, d os.DirEntry, err error) error {
-			if err != nil {
-				return err
-			}
-			if d.IsDir() {
-				return nil
-			}
-			name := strings.TrimSpace(d.Name())
-			if name == "" {
-				return nil
-			}
-			files = append(files, path)
-			return nil
-		})
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		entries, err := os.ReadDir(folder)
-		if err != nil {
-			return nil, err
-		}
-		for _, e := range entries {
-			if e.IsDir() {
-				continue
-			}
-			name := strings.TrimSpace(e.Name())
-			if name == "" {
-				continue
-			}
-			files = append(files, filepath.Join(folder, name))
-		}
+		return []string{colCheck, colOriginal, colPreview, colStatus, colFolder, colSize, colModified}
 	}
+	return []string{colCheck, colOriginal, colPreview, colStatus, colSize, colModified}
+}
 
-	sort.Strings(files)
-	return files, nil
+var previewColumnWidths = map[string]float32{
+	colCheck: 40, colOriginal: 240, colPreview: 260, colStatus: 170,
+	colFolder: 150, colSize: 80, colModified: 140,
 }
 
-/* -------------------- Filter engine -------------------- */
+// previewRow is what the preview says about full: its new name (after the
+// collision policy, for selected files) and a status, "⚠ …" when something
+// stops the rename.
+func previewRow(state *AppState, full string, stepErr error) (newName, status string) {
+	newName = state.batch.Name(full)
+	it, planned := state.plan[full]
+	if planned {
+		newName = it.NewName
+	}
 
-func filterFilesMulti(all []string, rules []FilterRule, matchAll bool, caseSensitive bool) []string {
-	out := make([]string, 0, len(all))
-	for _, full := range all {
-		base := filepath.Base(full)
-		if matchesRules(base, rules, matchAll, caseSensitive) {
-			out = append(out, full)
+	if stepErr != nil {
+		return newName, "⚠ " + stepErr.Error()
+	}
+	if reason := engine.InvalidPathReason(filepath.Dir(full), newName, state.profile); reason != "" {
+		return newName, "⚠ " + reason
+	}
+	if planned {
+		// only selected files are planned, so only they show conflicts
+		switch {
+		case it.Reason == engine.ReasonDuplicate:
+			return newName, "⚠ conflict"
+		case it.Reason == engine.ReasonTargetExists:
+			return newName, "⚠ target exists"
+		case it.Reason == engine.ReasonMissing:
+			return newName, "⚠ missing"
+		case it.Warning != "":
+			return newName, "⚠ " + it.Warning
+		case strings.HasPrefix(it.Reason, engine.ReasonCollision):
+			mark := "↻"
+			if it.Status != engine.StatusOK {
+				mark = "⚠"
+			}
+			return newName, mark + " " + strings.TrimPrefix(it.Reason, engine.ReasonCollision)
 		}
 	}
-	sort.Strings(out)
-	return out
-}
-
-func matchesRules(filename string, rules []FilterRule, matchAll bool, caseSensitive bool) bool {
-	if len(rules) == 0 {
-		return true
+	switch {
+	case state.deselected[full]:
+		return newName, "not selected"
+	case newName == filepath.Base(full):
+		return newName, "unchanged"
 	}
+	return newName, "rename"
+}
 
-	name := filename
-	if !caseSensitive {
-		name = strings.ToLower(name)
+// previewCell is the text of one preview table cell.
+func previewCell(state *AppState, full, col string, stepErr error) string {
+	f := state.fileByPath[full]
+	switch col {
+	case colOriginal:
+		return filepath.Base(full)
+	case colPreview:
+		name, _ := previewRow(state, full, stepErr)
+		return name
+	case colStatus:
+		_, status := previewRow(state, full, stepErr)
+		return status
+	case colFolder:
+		return relFolder(state.folderPath, full)
+	case colSize:
+		if f.Info != nil {
+			return engine.HumanSize(f.Info.Size())
+		}
+	case colModified:
+		if f.Info != nil {
+			return f.Info.ModTime().Format("2006-01-02 15:04")
+		}
 	}
+	return ""
+}
 
-	ruleMatch := func(r FilterRule) bool {
-		val := strings.TrimSpace(r.Value)
-		if val == "" {
-			return true
-		}
-		check := name
-		v := val
-		if !caseSensitive {
-			v = strings.ToLower(val)
+// arrangePreview fills state.viewFiles with the matches state.view shows,
+// ordered by the sorted column. Names sort naturally ("file2" before
+// "file10"); ties and unsorted views keep path order.
+func arrangePreview(state *AppState, stepErr error) {
+	view := state.filteredFiles
+	if state.view != "" && state.view != viewAll {
+		view = nil
+		for _, p := range state.filteredFiles {
+			name, status := previewRow(state, p, stepErr)
+			changed := name != filepath.Base(p)
+			switch state.view {
+			case viewChanged:
+				if !changed {
+					continue
+				}
+			case viewProblems:
+				if !strings.HasPrefix(status, "⚠") {
+					continue
+				}
+			case viewUnchanged:
+				if changed {
+					continue
+				}
+			}
+			view = append(view, p)
 		}
+	}
+	if state.sortCol == "" {
+		state.viewFiles = view
+		return
+	}
 
-		switch r.Mode {
-		case "contains":
-			return strings.Contains(check, v)
-		case "starts with":
-			return strings.HasPrefix(check, v)
-		case "ends with":
-			return strings.HasSuffix(check, v)
-		case "extension":
-			ext := filepath.Ext(filename)
-			vx := v
-			if !strings.HasPrefix(vx, ".") {
-				vx = "." + vx
+	type row struct {
+		path string
+		key  string
+		n    int64 // size or modification time; -1 when unknown
+	}
+	rows := make([]row, len(view))
+	for i, p := range view {
+		r := row{path: p, n: -1}
+		info := state.fileByPath[p].Info
+		switch state.sortCol {
+		case colSize:
+			if info != nil {
+				r.n = info.Size()
 			}
-			if !caseSensitive {
-				ext = strings.ToLower(ext)
-				vx = strings.ToLower(vx)
+		case colModified:
+			if info != nil {
+				r.n = info.ModTime().UnixNano()
 			}
-			return ext == vx
 		default:
-			return strings.Contains(check, v)
+			r.key = previewCell(state, p, state.sortCol, stepErr)
 		}
+		rows[i] = r
 	}
-
-	if matchAll {
-		for _, r := range rules {
-			if !ruleMatch(r) {
-				return false
-			}
+	slices.SortStableFunc(rows, func(a, b row) int {
+		c := engine.NaturalCompare(a.key, b.key)
+		if c == 0 {
+			c = cmp.Compare(a.n, b.n)
+		}
+		if state.sortDesc {
+			return -c
 		}
-		return true
+		return c
+	})
+	sorted := make([]string, len(rows))
+	for i, r := range rows {
+		sorted[i] = r.path
 	}
+	state.viewFiles = sorted
+}
+
+// Preview views: which of the matches the table lists.
+const (
+	viewAll       = "All matches"
+	viewChanged   = "Changed only"
+	viewProblems  = "Problems only"
+	viewUnchanged = "Unchanged only"
+)
 
-	for _, r := range rules {
-		if ruleMatch(r) {
-			return true
+var previewViews = []string{viewAll, viewChanged, viewProblems, viewUnchanged}
+
+// diffSegments spells out the change from old to new: removed characters
+// red, inserted ones green. Fyne text can't be struck through, so removed
+// characters are also set in italics to tell them apart without colour.
+func diffSegments(old, new string) []widget.RichTextSegment {
+	spans := engine.DiffNames(old, new)
+	segs := make([]widget.RichTextSegment, 0, len(spans))
+	for _, sp := range spans {
+		style := widget.RichTextStyleInline
+		switch sp.Op {
+		case engine.DiffDelete:
+			style.ColorName = theme.ColorNameError
+			style.TextStyle.Italic = true
+		case engine.DiffInsert:
+			style.ColorName = theme.ColorNameSuccess
+			style.TextStyle.Bold = true
 		}
+		segs = append(segs, &widget.TextSegment{Text: sp.Text, Style: style})
 	}
-	return false
+	return segs
 }
 
-/* -------------------- Rename pipeline -------------------- */
+/* -------------------- small helpers -------------------- */
 
-func applyRenameSteps(original string, steps []RenameStep) string {
-	if len(steps) == 0 {
-		return original
+// defaultScriptKind picks the script format for the host OS.
+func defaultScriptKind() string {
+	if runtime.GOOS == "windows" {
+		return engine.ScriptPowerShell
 	}
-	name := original
+	return engine.ScriptSh
+}
 
-	for _, s := range steps {
-		switch s.Op {
-		case OpRemoveText:
-			if s.A != "" {
-				name = strings.ReplaceAll(name, s.A, "")
-			}
-		case OpReplaceText:
-			if s.A != "" {
-				name = strings.ReplaceAll(name, s.A, s.B)
-			}
-		case OpInsertBeforeExt, OpAppend:
-			base := strings.TrimSuffix(name, filepath.Ext(name))
-			ext := filepath.Ext(name)
-			name = base + s.A + ext
-		case OpPrepend:
-			base := strings.TrimSuffix(name, filepath.Ext(name))
-			ext := filepath.Ext(name)
-			name = s.A + base + ext
-		case OpChangeExt:
-			base := strings.TrimSuffix(name, filepath.Ext(name))
-			newExt := strings.TrimSpace(s.A)
-			if newExt == "" {
-				name = base
-			} else {
-				if !strings.HasPrefix(newExt, ".") {
-					newExt = "." + newExt
-				}
-				name = base + newExt
-			}
-		}
-	}
+// newInlineError returns a hidden label used to show a row's validation error.
+func newInlineError() *widget.Label {
+	l := widget.NewLabel("")
+	l.Importance = widget.DangerImportance
+	l.Wrapping = fyne.TextWrapWord
+	l.Hide()
+	return l
+}
 
-	return strings.TrimSpace(name)
+func setInlineError(l *widget.Label, err error) {
+	if err == nil {
+		l.SetText("")
+		l.Hide()
+		return
+	}
+	l.SetText("⚠ " + err.Error())
+	l.Show()
 }
 
-/* -------------------- Validations -------------------- */
+// relFolder is the folder of full relative to the scanned folder, as shown
+// in the preview: "./" or "raw/2024/".
+func relFolder(folder, full string) string {
+	dir := path.Dir(engine.RelPath(folder, full))
+	if dir == "." {
+		return "./"
+	}
+	return dir + "/"
+}
 
-func invalidNameReason(name string) string {
-	trim := strings.TrimSpace(name)
-	if trim == "" {
-		return "empty name"
+// scanSummary labels the scan options button with what the options leave
+// out, e.g. "Scan: depth 2, 2 excluded, .gitignore".
+func scanSummary(so engine.ScanOptions) string {
+	var parts []string
+	if so.MaxDepth > 0 {
+		parts = append(parts, fmt.Sprintf("depth %d", so.MaxDepth))
 	}
-	if strings.ContainsAny(trim, `<>:"/\|?*`) {
-		return "invalid characters"
+	if n := len(so.Exclude); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d excluded", n))
 	}
-	reserved := map[string]bool{
-		"CON": true, "PRN": true, "AUX": true, "NUL": true,
-		"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
-		"COM6": true, "COM7": true, "COM8": true, "COM9": true,
-		"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
-		"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+	if so.SkipHidden {
+		parts = append(parts, "no hidden")
 	}
-	base := strings.TrimSuffix(trim, filepath.Ext(trim))
-	if reserved[strings.ToUpper(base)] {
-		return "reserved filename"
+	if so.UseIgnore {
+		parts = append(parts, ".gitignore")
 	}
-	return ""
-}
-
-/* -------------------- Paging helpers -------------------- */
-
-func pageCount(total, pageSize int) int {
-	if pageSize <= 0 {
-		return 1
+	if so.FollowLinks {
+		parts = append(parts, "follows links")
 	}
-	if total == 0 {
-		return 1
+	if len(parts) == 0 {
+		return "Scan: everything…"
 	}
-	return (total + pageSize - 1) / pageSize
+	return "Scan: " + strings.Join(parts, ", ") + "…"
 }
 
-func clamp(v, lo, hi int) int {
-	if v < lo {
-		return lo
-	}
-	if v > hi {
-		return hi
+// filterPlaceholder hints at the values a filter mode takes.
+func filterPlaceholder(mode string) string {
+	switch mode {
+	case "glob":
+		return `glob… e.g. **/raw/*.CR2, *.jpg, 2024-*/**`
+	case "path":
+		return `path contains… e.g. raw/, 2024/holiday`
+	case "size":
+		return `size… e.g. >10MB, <500KB, 1MB-5MB`
+	case "modified", "created":
+		return `date… e.g. last 7 days, >2024-01-01, 2024-01-01..2024-03-31`
+	case "hidden", "read-only", "symlink":
+		return `yes or no (empty means yes)`
 	}
-	return v
+	return `value… e.g. The, Whale, png`
 }
 
-/* -------------------- small helpers -------------------- */
-
-func firstN[T any](in []T, n int) []T {
-	if len(in) <= n {
-		return in
+func stepOpOptions() []string {
+	opts := make([]string, len(engine.RenameOps))
+	for i, op := range engine.RenameOps {
+		opts[i] = string(op)
 	}
-	return in[:n]
+	return opts
 }
 
 func prettyPath(p string) string {
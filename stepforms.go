@@ -0,0 +1,217 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+
+	"FileRenUtil/engine"
+)
+
+/* -------------------- Step option forms -------------------- */
+
+// Extra controls for steps whose options don't fit in the A/B entries.
+// Each form edits a copy of the options and hands it to onChange.
+
+func numberingForm(num engine.NumberingOptions, onChange func(engine.NumberingOptions)) fyne.CanvasObject {
+	intEntry := func(label string, v int, set func(int)) *widget.Entry {
+		e := widget.NewEntry()
+		e.SetPlaceHolder(label)
+		e.SetText(strconv.Itoa(v))
+		e.Validator = func(s string) error {
+			if _, err := strconv.Atoi(strings.TrimSpace(s)); err != nil {
+				return errors.New("not a whole number")
+			}
+			return nil
+		}
+		e.OnChanged = func(s string) {
+			n, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil {
+				return
+			}
+			set(n)
+			onChange(num)
+		}
+		return e
+	}
+
+	start := intEntry("start", num.Start, func(n int) { num.Start = n })
+	inc := intEntry("increment", num.Increment, func(n int) { num.Increment = n })
+
+	pad := widget.NewEntry()
+	pad.SetPlaceHolder(`digits or "auto"`)
+	if num.Padding == engine.PadAuto {
+		pad.SetText("auto")
+	} else {
+		pad.SetText(strconv.Itoa(num.Padding))
+	}
+	pad.Validator = func(s string) error {
+		if _, ok := parsePadding(s); !ok {
+			return errors.New(`digits or "auto"`)
+		}
+		return nil
+	}
+	pad.OnChanged = func(s string) {
+		if n, ok := parsePadding(s); ok {
+			num.Padding = n
+			onChange(num)
+		}
+	}
+
+	position := widget.NewSelect(engine.NumberPositions, nil)
+	position.Selected = num.Position
+	position.OnChanged = func(sel string) {
+		num.Position = sel
+		onChange(num)
+	}
+
+	sortBy := widget.NewSelect(engine.NumberSortKeys, nil)
+	sortBy.Selected = num.SortBy
+	sortBy.OnChanged = func(sel string) {
+		num.SortBy = sel
+		onChange(num)
+	}
+
+	perFolder := widget.NewCheck("Restart in each folder", nil)
+	perFolder.Checked = num.PerFolder
+	perFolder.OnChanged = func(v bool) {
+		num.PerFolder = v
+		onChange(num)
+	}
+
+	return container.NewVBox(
+		container.NewGridWithColumns(3,
+			widget.NewLabel("Start"), widget.NewLabel("Increment"), widget.NewLabel("Padding"),
+			start, inc, pad,
+		),
+		container.NewGridWithColumns(2,
+			widget.NewLabel("Position"), widget.NewLabel("Order by"),
+			position, sortBy,
+		),
+		perFolder,
+	)
+}
+
+// parsePadding accepts a digit count or "auto".
+func parsePadding(s string) (int, bool) {
+	s = strings.TrimSpace(s)
+	if strings.EqualFold(s, "auto") {
+		return engine.PadAuto, true
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return n, true
+}
+
+func caseForm(c engine.CaseOptions, onChange func(engine.CaseOptions)) fyne.CanvasObject {
+	small := widget.NewEntry()
+	small.SetPlaceHolder("small words kept lower in Title Case")
+	small.SetText(c.SmallWords)
+	small.OnChanged = func(s string) {
+		c.SmallWords = s
+		onChange(c)
+	}
+	if c.Style != engine.CaseTitle {
+		small.Disable()
+	}
+
+	style := widget.NewSelect(engine.CaseStyles, nil)
+	style.Selected = c.Style
+	style.OnChanged = func(sel string) {
+		c.Style = sel
+		if sel == engine.CaseTitle {
+			small.Enable()
+		} else {
+			small.Disable()
+		}
+		onChange(c)
+	}
+
+	ext := widget.NewSelect(engine.ExtCaseStyles, nil)
+	ext.Selected = c.Ext
+	ext.OnChanged = func(sel string) {
+		c.Ext = sel
+		onChange(c)
+	}
+
+	return container.NewVBox(
+		container.NewGridWithColumns(2, style, ext),
+		small,
+	)
+}
+
+// unicodeForm edits the mode of a Normalize Unicode step, kept in its A.
+// Transliteration says which scripts it covers.
+func unicodeForm(mode string, onChange func(string)) fyne.CanvasObject {
+	note := widget.NewLabel("Spells " + engine.TranslitScripts + " letters in ASCII; other scripts (Chinese, Japanese, Korean, Indic, Thai, …) are left as they are.")
+	note.Wrapping = fyne.TextWrapWord
+	note.Importance = widget.LowImportance
+	if mode != engine.UnicodeTranslit {
+		note.Hide()
+	}
+
+	sel := widget.NewSelect(engine.UnicodeModes, nil)
+	sel.Selected = mode
+	sel.OnChanged = func(m string) {
+		if m == engine.UnicodeTranslit {
+			note.Show()
+		} else {
+			note.Hide()
+		}
+		onChange(m)
+	}
+	return container.NewVBox(sel, note)
+}
+
+// sanitizeForm edits the toggles of a Sanitize step. The slug option does
+// its own spacing and punctuation, so those toggles are off while it's on.
+func sanitizeForm(opts engine.SanitizeOptions, onChange func(engine.SanitizeOptions)) fyne.CanvasObject {
+	check := func(label string, v *bool) *widget.Check {
+		c := widget.NewCheck(label, nil)
+		c.Checked = *v
+		c.OnChanged = func(on bool) {
+			*v = on
+			onChange(opts)
+		}
+		return c
+	}
+	urlCheck := check("URL-decode (%20)", &opts.URLDecode)
+	spaceCheck := check("Collapse whitespace", &opts.CollapseSpace)
+	punctCheck := check("Trim punctuation", &opts.TrimPunct)
+	emojiCheck := check("Remove emoji", &opts.RemoveEmoji)
+	slugCheck := check("Slug (lower-case-ascii)", &opts.Slug)
+
+	cosmetic := func() {
+		if opts.Slug {
+			spaceCheck.Disable()
+			punctCheck.Disable()
+		} else {
+			spaceCheck.Enable()
+			punctCheck.Enable()
+		}
+	}
+	slugCheck.OnChanged = func(on bool) {
+		opts.Slug = on
+		cosmetic()
+		onChange(opts)
+	}
+	cosmetic()
+
+	invalid := widget.NewSelect(engine.SanitizeInvalidModes, nil)
+	invalid.Selected = opts.Invalid
+	if invalid.Selected == "" {
+		invalid.Selected = engine.SanitizeReplace
+	}
+	invalid.OnChanged = func(sel string) {
+		opts.Invalid = sel
+		onChange(opts)
+	}
+
+	return container.NewGridWithColumns(3, urlCheck, spaceCheck, punctCheck, emojiCheck, slugCheck, invalid)
+}